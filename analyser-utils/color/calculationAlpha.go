@@ -0,0 +1,76 @@
+package color
+
+import "image"
+
+/*
+===== COUVERTURE ALPHA D'UNE IMAGE =====
+
+À QUOI ÇA SERT :
+Mesure la part opaque d'une image : 1 pour une photo, beaucoup moins pour
+un logo ou un sticker détouré sur fond transparent. Deux logos de même
+silhouette ont une couverture proche, quelle que soit leur couleur.
+
+Retour :
+- Moyenne de l'opacité des pixels, entre 0.0 (transparent) et 1.0 (opaque)
+*/
+func ComputeAlphaCoverage(img image.Image) float64 {
+	bounds := img.Bounds()
+	total := float64(bounds.Dx() * bounds.Dy())
+	if total == 0 {
+		return 0
+	}
+
+	var sum float64
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			_, _, _, a := img.At(x, y).RGBA()
+			sum += float64(a) / 0xffff
+		}
+	}
+	return sum / total
+}
+
+/*
+===== ANALYSE DES SEULS PIXELS VISIBLES =====
+
+À QUOI ÇA SERT :
+Les fonctions classiques lisent les valeurs prémultipliées de RGBA() :
+un pixel transparent y compte comme un pixel NOIR, et le fond d'un logo
+détouré domine ses histogrammes et sa couleur moyenne.
+Ces variantes ignorent les pixels totalement transparents et retrouvent
+la vraie couleur des pixels semi-transparents (division par l'alpha).
+
+Une zone entièrement transparente donne des histogrammes vides et une
+couleur moyenne nulle.
+*/
+func ComputeHistogramRGBVisible(img image.Image) map[string][]int {
+	return computeHistogramRGB(img, true)
+}
+
+// ComputeHistogramHSVVisible : ComputeHistogramHSV sur les seuls pixels visibles
+func ComputeHistogramHSVVisible(img image.Image) map[string][]int {
+	return computeHistogramHSV(img, true)
+}
+
+// ComputeMeanColorVisible : ComputeMeanColor sur les seuls pixels visibles
+func ComputeMeanColorVisible(img image.Image) [3]float64 {
+	return computeMeanColor(img, true)
+}
+
+/*
+===== LECTURE D'UN PIXEL =====
+
+Retourne les composantes 16 bits du pixel (x, y), prémultipliées comme
+RGBA() sans visibleOnly. Avec visibleOnly, ok est faux pour un pixel
+totalement transparent et les composantes sont divisées par l'alpha.
+*/
+func readPixel(img image.Image, x, y int, visibleOnly bool) (r, g, b uint32, ok bool) {
+	r, g, b, a := img.At(x, y).RGBA()
+	if !visibleOnly || a == 0xffff {
+		return r, g, b, true
+	}
+	if a == 0 {
+		return 0, 0, 0, false
+	}
+	return r * 0xffff / a, g * 0xffff / a, b * 0xffff / a, true
+}
@@ -21,13 +21,19 @@ Retour :
 - Précision float64 pour éviter les erreurs d'arrondi
 */
 func ComputeMeanColor(img image.Image) [3]float64 {
+	return computeMeanColor(img, false)
+}
+
+// computeMeanColor : visibleOnly ignore les pixels totalement transparents (voir ComputeMeanColorVisible)
+func computeMeanColor(img image.Image, visibleOnly bool) [3]float64 {
 	var rSum, gSum, bSum float64
 
 	bounds := img.Bounds() // Bounds méthode qui récupere les images (implémenter de base sur Go)
 
 	// Dx() = Delta X = largeur (bounds.Max.X - bounds.Min.X)
 	// Dy() = Delta Y = hauteur (bounds.Max.Y - bounds.Min.Y)
-	total := float64(bounds.Dx() * bounds.Dy())
+	// Avec visibleOnly, seuls les pixels visibles sont comptés
+	total := 0.0
 
 	// OPTIMISATION : Parcours séquentiel optimal pour la cache mémoire du CPU
 	for y := bounds.Min.Y; y < bounds.Max.Y; y++ { // Pour chaque ligne (rangée de pixels)
@@ -37,7 +43,11 @@ func ComputeMeanColor(img image.Image) [3]float64 {
 			// - img.At(x,y) : Méthode standard Go pour lire un pixel
 			// - .RGBA() : Extrait Rouge, Vert, Bleu, Alpha (transparence)
 			// - Valeurs 16 bits : Go utilise uint16 (0-65535) pour plus de précision
-			r, g, b, _ := img.At(x, y).RGBA()
+			r, g, b, ok := readPixel(img, x, y, visibleOnly)
+			if !ok {
+				continue // Pixel transparent ignoré
+			}
+			total++
 
 			rSum += float64(r >> 8) // Conversion 16→8 bits + accumulation Rouge
 			gSum += float64(g >> 8) // Conversion 16→8 bits + accumulation Vert
@@ -47,5 +57,8 @@ func ComputeMeanColor(img image.Image) [3]float64 {
 
 	// Calcul des moyennes finales en divisant les sommes par le nombre total de pixels
 	// RÉSULTAT : Couleur moyenne de l'image sous forme [Rouge, Vert, Bleu]
+	if total == 0 {
+		return [3]float64{} // Zone entièrement transparente : pas de couleur
+	}
 	return [3]float64{rSum / total, gSum / total, bSum / total}
 }
@@ -26,6 +26,11 @@ Retour :
 - Chaque histogramme a config.Bins valeurs (64 par défaut)
 */
 func ComputeHistogramHSV(img image.Image) map[string][]int {
+	return computeHistogramHSV(img, false)
+}
+
+// computeHistogramHSV : visibleOnly ignore les pixels totalement transparents (voir ComputeHistogramHSVVisible)
+func computeHistogramHSV(img image.Image, visibleOnly bool) map[string][]int {
 
 	// Création des 3 histogrammes vides avec la taille configurée
 	// POURQUOI 3 histogrammes séparés ?
@@ -40,7 +45,10 @@ func ComputeHistogramHSV(img image.Image) map[string][]int {
 		for x := bounds.Min.X; x < bounds.Max.X; x++ {
 
 			// Lecture du pixel et conversion RGB → HSV
-			r, g, b, _ := img.At(x, y).RGBA()
+			r, g, b, ok := readPixel(img, x, y, visibleOnly)
+			if !ok {
+				continue // Pixel transparent ignoré
+			}
 			h, s, v := rgbToHsv(uint8(r>>8), uint8(g>>8), uint8(b>>8))
 
 			// Calcul des index pour chaque composante HSV
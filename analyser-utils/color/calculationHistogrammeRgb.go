@@ -25,6 +25,11 @@ Retour :
 - Chaque histogramme a config.Bins valeurs (64 par défaut)
 */
 func ComputeHistogramRGB(img image.Image) map[string][]int {
+	return computeHistogramRGB(img, false)
+}
+
+// computeHistogramRGB : visibleOnly ignore les pixels totalement transparents (voir ComputeHistogramRGBVisible)
+func computeHistogramRGB(img image.Image, visibleOnly bool) map[string][]int {
 
 	// Création des 3 histogrammes vides pour Rouge, Vert, Bleu
 	// POURQUOI 3 histogrammes séparés ?
@@ -39,7 +44,10 @@ func ComputeHistogramRGB(img image.Image) map[string][]int {
 		for x := bounds.Min.X; x < bounds.Max.X; x++ {
 
 			// Lecture du pixel et classification directe dans les bins
-			r, g, b, _ := img.At(x, y).RGBA()
+			r, g, b, ok := readPixel(img, x, y, visibleOnly)
+			if !ok {
+				continue // Pixel transparent ignoré
+			}
 
 			// FORMULE DE BINNING : (valeur_8_bits * Bins) / 256
 			// EXPLICATION :
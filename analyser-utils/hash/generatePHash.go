@@ -1,7 +1,6 @@
 package hash
 
 import (
-	"fmt"
 	"github.com/MrIsmail1/Golang_images_matcher/analyser-utils/math"
 	drawx "golang.org/x/image/draw"
 	"image"
@@ -30,9 +29,9 @@ Paramètre :
 - img : image à traiter (toute taille, tout format)
 
 Retour :
-- Hash hexadécimal de 16 caractères (64 bits)
+- Hash de 64 bits (bit i = coefficient DCT n°i au-dessus de la moyenne)
 */
-func GeneratePHash(img image.Image) string {
+func GeneratePHash(img image.Image) uint64 {
 
 	// ============================================================================================
 	// ÉTAPE 1 : STANDARDISATION (32x32 niveaux de gris)
@@ -44,8 +43,19 @@ func GeneratePHash(img image.Image) string {
 	// Création d'une image en niveaux de gris 32x32
 	gray := image.NewGray(image.Rect(0, 0, size, size))
 	drawx.ApproxBiLinear.Scale(gray, gray.Bounds(), img, img.Bounds(), draw.Over, nil)
-	dctVals := math.Dct2D(gray)
+	return PHashFromDCT(math.Dct2D(gray))
+}
+
+/*
+===== BINARISATION DES COEFFICIENTS DCT =====
 
+Bit i du hash = coefficient n°i du bloc 8×8 des basses fréquences
+au-dessus de leur moyenne (coefficient DC exclu de la moyenne).
+
+Paramètre :
+- dctVals : coefficients DCT d'une image 32×32, indexés [y][x]
+*/
+func PHashFromDCT(dctVals [][]float64) uint64 {
 	avg := averageDCT(dctVals)
 
 	// Variable pour construire le hash binaire 64 bits
@@ -69,6 +79,5 @@ func GeneratePHash(img image.Image) string {
 		}
 	}
 
-	// FORMAT : %016x = hex sur exactement 16 caractères avec zéros initiaux
-	return fmt.Sprintf("%016x", hash)
+	return hash
 }
@@ -51,14 +51,44 @@ PRINCIPE DE LA DCT :
 - Hautes fréquences (coin inf-droit) = détails fins, textures, bruit
 - Concentre l'énergie dans les premiers coefficients
 
+OPTIMISATION (DCT SÉPARABLE) :
+La DCT 2D se décompose en deux passes de DCT 1D :
+1. Une DCT 1D sur chaque ligne (fréquences horizontales u)
+2. Une DCT 1D sur chaque colonne du résultat (fréquences verticales v)
+Les cosinus sont précalculés une seule fois dans dctCos.
+Coût : O(N³) additions au lieu de O(N⁴) appels à math.Cos.
+Les coefficients sont identiques à ceux de la formule directe (aux arrondis
+flottants près, voir les tests du paquet) : les pHash existants restent stables.
+
 Paramètre :
 - img : image en niveaux de gris 32×32 pixels
 
 Retour :
-- Matrice 32×32 des coefficients DCT
+- Matrice 32×32 des coefficients DCT, indexée [u][v]
 */
 func Dct2D(img *image.Gray) [][]float64 {
-	N := 32 // Taille de la matrice (32×32 standard pour pHash)
+	const N = dctSize
+
+	// Lecture des pixels une seule fois : pixels[y][x]
+	var pixels [N][N]float64
+	for y := 0; y < N; y++ {
+		for x := 0; x < N; x++ {
+			pixels[y][x] = float64(img.GrayAt(x, y).Y)
+		}
+	}
+
+	// PASSE 1 : DCT 1D horizontale de chaque ligne
+	// rows[y][u] = Σx pixel(x,y) × cos((2x+1)uπ/2N)
+	var rows [N][N]float64
+	for y := 0; y < N; y++ {
+		for u := 0; u < N; u++ {
+			var sum float64
+			for x := 0; x < N; x++ {
+				sum += pixels[y][x] * dctCos[u][x]
+			}
+			rows[y][u] = sum
+		}
+	}
 
 	// Initialisation de la matrice de coefficients DCT
 	dct := make([][]float64, N)
@@ -66,44 +96,43 @@ func Dct2D(img *image.Gray) [][]float64 {
 		dct[i] = make([]float64, N)
 	}
 
-	// CALCUL DE LA DCT 2D COMPLÈTE
-	// Double boucle sur les fréquences de sortie (u,v)
-	// (u,v) = coordonnées dans l'espace fréquentiel
-	for u := 0; u < N; u++ { // Pour chaque fréquence horizontale
-		for v := 0; v < N; v++ { // Pour chaque fréquence verticale
-
+	// PASSE 2 : DCT 1D verticale de chaque colonne du résultat
+	// dct[u][v] = Σy rows[y][u] × cos((2y+1)vπ/2N), puis normalisation
+	for u := 0; u < N; u++ {
+		for v := 0; v < N; v++ {
 			var sum float64
-
-			// Double boucle sur les pixels d'entrée (x,y)
-			// (x,y) = coordonnées dans l'espace spatial (image originale)
-			for x := 0; x < N; x++ { // Pour chaque colonne de l'image
-				for y := 0; y < N; y++ { // Pour chaque ligne de l'image
-
-					// Lecture de l'intensité du pixel
-					pixel := float64(img.GrayAt(x, y).Y)
-
-					// Application de la formule DCT 2D
-					// FORMULE : DCT(u,v) = Σ Σ f(x,y) × cos(...) × cos(...)
-					// Les cosinus créent les "bases de fréquence" 2D
-					sum += pixel *
-						math.Cos((2*float64(x)+1)*float64(u)*math.Pi/float64(2*N)) * // Cosinus horizontal
-						math.Cos((2*float64(y)+1)*float64(v)*math.Pi/float64(2*N)) // Cosinus vertical
-				}
+			for y := 0; y < N; y++ {
+				sum += rows[y][u] * dctCos[v][y]
 			}
 
-			cu, cv := 1.0, 1.0
-			if u == 0 {
-				cu = 1 / math.Sqrt(2) // Facteur spécial pour fréquence nulle horizontale
-			}
-			if v == 0 {
-				cv = 1 / math.Sqrt(2) // Facteur spécial pour fréquence nulle verticale
-			}
-
-			// Coefficient DCT final avec normalisation
-			// FACTEUR 0.25 = 1/(2×2) pour normaliser la DCT 2D
-			dct[u][v] = 0.25 * cu * cv * sum
+			// Même normalisation que la formule directe : 0.25 × C(u) × C(v)
+			dct[u][v] = 0.25 * dctScale[u] * dctScale[v] * sum
 		}
 	}
 
 	return dct
 }
+
+// dctSize : taille de la matrice DCT (32×32 standard pour pHash)
+const dctSize = 32
+
+/*
+===== TABLES PRÉCALCULÉES DE LA DCT =====
+
+dctCos[k][n] = cos((2n+1)kπ/2N) : base cosinus de la fréquence k au pixel n
+dctScale[k]  = C(k) : 1/√2 pour la fréquence nulle, 1 sinon
+*/
+var dctCos, dctScale = dctTables()
+
+func dctTables() ([dctSize][dctSize]float64, [dctSize]float64) {
+	var cos [dctSize][dctSize]float64
+	var scale [dctSize]float64
+	for k := 0; k < dctSize; k++ {
+		for n := 0; n < dctSize; n++ {
+			cos[k][n] = math.Cos((2*float64(n) + 1) * float64(k) * math.Pi / float64(2*dctSize))
+		}
+		scale[k] = 1.0
+	}
+	scale[0] = 1 / math.Sqrt(2)
+	return cos, scale
+}
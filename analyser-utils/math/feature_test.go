@@ -0,0 +1,133 @@
+package math_test
+
+import (
+	"image"
+	"image/draw"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/MrIsmail1/Golang_images_matcher/analyser-utils/hash"
+	imath "github.com/MrIsmail1/Golang_images_matcher/analyser-utils/math"
+	"github.com/MrIsmail1/Golang_images_matcher/internal/testbank"
+	drawx "golang.org/x/image/draw"
+)
+
+// bankImage : image de la banque et sa réduction 32×32 en niveaux de gris, comme dans GeneratePHash
+type bankImage struct {
+	img  image.Image
+	gray *image.Gray
+}
+
+// bankImages décode les images de la banque
+func bankImages(tb testing.TB) []bankImage {
+	tb.Helper()
+	testbank.Require(tb)
+	entries, err := os.ReadDir(testbank.Dir)
+	if err != nil {
+		tb.Skipf("banque illisible : %v", err)
+	}
+
+	var images []bankImage
+	for _, e := range entries {
+		file, err := os.Open(filepath.Join(testbank.Dir, e.Name()))
+		if err != nil {
+			continue
+		}
+		img, _, err := image.Decode(file)
+		file.Close()
+		if err != nil {
+			continue // Pas une image
+		}
+		gray := image.NewGray(image.Rect(0, 0, 32, 32))
+		drawx.ApproxBiLinear.Scale(gray, gray.Bounds(), img, img.Bounds(), draw.Over, nil)
+		images = append(images, bankImage{img: img, gray: gray})
+	}
+	if len(images) == 0 {
+		tb.Skip("aucune image lisible dans la banque")
+	}
+	return images
+}
+
+/*
+===== DCT 2D NAÏVE (RÉFÉRENCE) =====
+
+Implémentation directe de la formule, en O(N⁴) avec un math.Cos par terme :
+la version historique du pHash, gardée ici pour vérifier et mesurer Dct2D.
+DCT(u,v) = ¼ × C(u) × C(v) × Σ Σ f(x,y) × cos((2x+1)uπ/2N) × cos((2y+1)vπ/2N)
+*/
+func dct2DNaive(img *image.Gray) [][]float64 {
+	const N = 32
+
+	dct := make([][]float64, N)
+	for i := range dct {
+		dct[i] = make([]float64, N)
+	}
+	for u := 0; u < N; u++ {
+		for v := 0; v < N; v++ {
+			var sum float64
+			for x := 0; x < N; x++ {
+				for y := 0; y < N; y++ {
+					sum += float64(img.GrayAt(x, y).Y) *
+						math.Cos((2*float64(x)+1)*float64(u)*math.Pi/float64(2*N)) *
+						math.Cos((2*float64(y)+1)*float64(v)*math.Pi/float64(2*N))
+				}
+			}
+
+			cu, cv := 1.0, 1.0
+			if u == 0 {
+				cu = 1 / math.Sqrt(2)
+			}
+			if v == 0 {
+				cv = 1 / math.Sqrt(2)
+			}
+			dct[u][v] = 0.25 * cu * cv * sum
+		}
+	}
+	return dct
+}
+
+/*
+===== DCT SÉPARABLE ET FORMULE DIRECTE =====
+
+Dct2D doit donner les mêmes coefficients que la formule directe (aux
+arrondis près), et GeneratePHash les mêmes bits qu'avec la formule
+directe : les pHash déjà enregistrés restent valables.
+*/
+func TestDct2DMatchesNaive(t *testing.T) {
+	for i, b := range bankImages(t) {
+		fast, naive := imath.Dct2D(b.gray), dct2DNaive(b.gray)
+		for u := range naive {
+			for v := range naive[u] {
+				if d := math.Abs(fast[u][v] - naive[u][v]); d > 1e-9 {
+					t.Fatalf("image %d : coefficient [%d][%d] écart %g", i, u, v, d)
+				}
+			}
+		}
+		if got, want := hash.GeneratePHash(b.img), hash.PHashFromDCT(naive); got != want {
+			t.Errorf("image %d : pHash %016x, %016x avec la formule directe", i, got, want)
+		}
+	}
+}
+
+// Formule directe O(N⁴) : référence
+func BenchmarkDct2DNaive(b *testing.B) {
+	images := bankImages(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		dct2DNaive(images[i%len(images)].gray)
+	}
+}
+
+// DCT séparable, cosinus précalculés
+func BenchmarkDct2D(b *testing.B) {
+	images := bankImages(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		imath.Dct2D(images[i%len(images)].gray)
+	}
+}
@@ -1,6 +1,10 @@
 package analyzer
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
 	"github.com/MrIsmail1/Golang_images_matcher/analyser-utils/color"
 	"github.com/MrIsmail1/Golang_images_matcher/analyser-utils/hash"
 	"github.com/MrIsmail1/Golang_images_matcher/analyser-utils/shape"
@@ -11,6 +15,7 @@ import (
 	_ "image/gif"
 	_ "image/jpeg"
 	_ "image/png"
+	"math"
 	"os"
 	"path/filepath"
 
@@ -33,6 +38,9 @@ STRATÉGIE MULTI-ÉCHELLES :
   - Détecte les variations régionales dans l'image
   - Complète la vision globale avec des détails fins
 
+3. PYRAMIDE SPATIALE (optionnelle) : grilles supplémentaires (ex. 2×2, 4×4)
+  - Échelles intermédiaires, plus tolérantes aux décalages
+
 Paramètre :
 - imagePath : chemin vers l'image à analyser
 
@@ -41,17 +49,45 @@ Retour :
 - Erreur si problème de lecture/analyse
 */
 func AnalyzeImage(imagePath string) (*model.FullImageDescriptor, error) {
+	return AnalyzeImageWith(imagePath, model.CurrentParams())
+}
+
+/*
+===== ANALYSE AVEC DES PARAMÈTRES CHOISIS =====
+
+Comme AnalyzeImage, avec une taille standard, une grille et un mode de
+normalisation choisis (voir model.NormalizeMode). Le nombre de bins des
+histogrammes est fixé à la compilation (config.Bins).
+Les paramètres sont enregistrés dans le descripteur produit.
+*/
+func AnalyzeImageWith(imagePath string, params model.AnalysisParams) (*model.FullImageDescriptor, error) {
+	if err := params.Validate(); err != nil {
+		return nil, err
+	}
+	if params.Bins != config.Bins {
+		return nil, fmt.Errorf("histogrammes de %d bins non supportés (%d à la compilation)", params.Bins, config.Bins)
+	}
 
-	// Ouverture du fichier avec gestion d'erreur
-	file, err := os.Open(imagePath)
+	// Lecture complète du fichier : les mêmes octets servent au décodage
+	// et à l'empreinte SHA-256 (cache invalidé si l'image change)
+	data, err := os.ReadFile(imagePath)
 	if err != nil {
 		return nil, err // Fichier inexistant, permissions insuffisantes, etc.
 	}
-	defer file.Close() // Fermeture automatique même en cas d'erreur
+	stat, err := os.Stat(imagePath)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	source := &model.SourceInfo{
+		Size:    int64(len(data)),
+		ModTime: stat.ModTime().UTC(),
+		SHA256:  hex.EncodeToString(sum[:]),
+	}
 
 	// Décodage automatique du format (JPEG/PNG/GIF grâce aux imports _)
 	// MAGIE : image.Decode détecte automatiquement le format depuis les premiers bytes
-	srcImg, _, err := image.Decode(file)
+	srcImg, _, err := image.Decode(bytes.NewReader(data))
 	if err != nil {
 		return nil, err // Image corrompue, format non supporté, etc.
 	}
@@ -61,67 +97,240 @@ func AnalyzeImage(imagePath string) (*model.FullImageDescriptor, error) {
 	// - Base de comparaison uniforme entre toutes les images
 	// - Performance prévisible (même temps de traitement)
 	// - Descripteurs comparables (même échelle)
-	resized := image.NewRGBA(image.Rect(0, 0, config.StandardSize, config.StandardSize))
-	drawx.ApproxBiLinear.Scale(resized, resized.Bounds(), srcImg, srcImg.Bounds(), draw.Over, nil)
+	normalized := normalizeImage(srcImg, params)
+
+	// Traitement de la transparence (voir model.AlphaMode)
+	// La couverture alpha est mesurée sur l'image normalisée, avant ce traitement
+	resized, err := applyAlpha(normalized, params)
+	if err != nil {
+		return nil, err
+	}
+	colors := colorFuncsFor(params.Alpha)
 
 	// Délégation aux modules spécialisés pour chaque type d'analyse
 	// AVANTAGE : Chaque module fait ce qu'il sait le mieux faire
 
-	globalRGB := color.ComputeHistogramRGB(resized)           // Distribution des couleurs RGB
-	globalHSV := color.ComputeHistogramHSV(resized)           // Distribution des couleurs HSV (complémentaire)
-	globalPHash := hash.GeneratePHash(resized)                // Signature binaire robuste 64 bits
-	globalMean := color.ComputeMeanColor(resized)             // Couleur dominante simple
+	globalRGB := colors.rgb(resized)                          // Distribution des couleurs RGB
+	globalHSV := colors.hsv(resized)                          // Distribution des couleurs HSV (complémentaire)
+	globalPHash := model.PHash(hash.GeneratePHash(resized))   // Signature binaire robuste 64 bits
+	globalMean := colors.mean(resized)                        // Couleur dominante simple
 	globalTexture := texture.ComputeTextureSignature(resized) // Rugosité/finesse globale
 	globalShape := shape.ComputeShapeSignature(resized)       // Densité de contours/formes
+	globalAlpha := color.ComputeAlphaCoverage(normalized)     // Part opaque de l'image
+
+	// Analyse locale : grille TilesPerRow×TilesPerRow (9×9 = 81 tuiles par défaut)
+	tiles := analyzeGrid(resized, normalized, params.TilesPerRow, params, colors)
+
+	// Niveaux de la pyramide spatiale : mêmes analyses sur des grilles
+	// plus grossières ou plus fines (voir model.PyramidLevel)
+	var levels []model.PyramidLevel
+	for _, grid := range params.PyramidGrids() {
+		levels = append(levels, model.PyramidLevel{
+			Grid:  grid,
+			Tiles: analyzeGrid(resized, normalized, grid, params, colors),
+		})
+	}
+
+	// Construction de la structure finale qui contient TOUT
+	// ORGANISATION :
+	// - Métadonnées : version du schéma, paramètres d'analyse, nom et empreinte du fichier
+	// - Niveau global : caractéristiques de l'image entière
+	// - Niveau local : 81 tuiles avec leurs caractéristiques individuelles
+	// - Pyramide : grilles supplémentaires éventuelles
+	desc := &model.FullImageDescriptor{
+		SchemaVersion:       model.SchemaVersion,      // Version du format JSON
+		Params:              params,                   // Réglages utilisés pour cette analyse
+		ImageName:           filepath.Base(imagePath), // Nom du fichier seulement (sans chemin)
+		Source:              source,                   // Empreinte du fichier analysé
+		GlobalRGB:           globalRGB,                // Couleurs globales RGB
+		GlobalHSV:           globalHSV,                // Couleurs globales HSV
+		GlobalPHash:         globalPHash,              // Signature structurelle globale
+		GlobalMeanColor:     globalMean,               // Teinte dominante globale
+		GlobalTexture:       globalTexture,            // Rugosité globale
+		GlobalShape:         globalShape,              // Richesse en formes globale
+		GlobalAlphaCoverage: globalAlpha,              // Part opaque globale
+		Tiles:               tiles,                    // Collection des 81 tuiles analysées
+		Levels:              levels,                   // Niveaux de la pyramide (aucun par défaut)
+	}
 
-	// Calcul de la taille d'une tuile individuelle
-	// EXEMPLE : 256 pixels ÷ 9 tuiles = ~28 pixels par tuile
-	tileSize := config.StandardSize / config.TilesPerRow
-	var tiles []model.TileDescriptor
+	return desc, nil // Mission accomplie ! Descripteur complet prêt à l'emploi
+}
 
-	// Double boucle pour créer la grille 9×9 de tuiles
-	for ty := 0; ty < config.TilesPerRow; ty++ { // Pour chaque ligne de tuiles
-		for tx := 0; tx < config.TilesPerRow; tx++ { // Pour chaque colonne de tuiles
+/*
+===== ANALYSE D'UNE GRILLE DE TUILES =====
+
+Découpe l'image en grid×grid tuiles (voir tileSpans) et applique à chacune
+les MÊMES analyses que pour l'image globale. Tuiles retournées ligne par ligne.
+La couverture alpha est lue sur normalized, l'image avant traitement de la
+transparence.
+*/
+func analyzeGrid(resized, normalized *image.RGBA, grid int, params model.AnalysisParams, colors colorFuncs) []model.TileDescriptor {
+	// Calcul des bornes des tuiles, colonne par colonne et ligne par ligne
+	// EXEMPLE : 256 pixels ÷ 9 tuiles = ~28 pixels par tuile (voir model.TilingMode)
+	// (mode fit : largeur et hauteur de tuile diffèrent si l'image n'est pas carrée)
+	cols := tileSpans(resized.Bounds().Dx(), grid, params)
+	rows := tileSpans(resized.Bounds().Dy(), grid, params)
+	tiles := make([]model.TileDescriptor, 0, grid*grid)
+
+	// Double boucle pour créer la grille (9×9 pour la grille par défaut)
+	for ty := 0; ty < grid; ty++ { // Pour chaque ligne de tuiles
+		for tx := 0; tx < grid; tx++ { // Pour chaque colonne de tuiles
 
 			// Extraction de la portion d'image correspondant à cette tuile
-			// COORDONNÉES : (tx*tileSize, ty*tileSize) vers ((tx+1)*tileSize, (ty+1)*tileSize)
-			tileImg := resized.SubImage(image.Rect(
-				tx*tileSize, ty*tileSize, // Coin supérieur gauche
-				(tx+1)*tileSize, (ty+1)*tileSize, // Coin inférieur droit
-			))
+			tileRect := image.Rect(
+				cols[tx].start, rows[ty].start, // Coin supérieur gauche
+				cols[tx].end, rows[ty].end, // Coin inférieur droit
+			)
+			tileImg := resized.SubImage(tileRect)
 
 			// Application des MÊMES analyses que pour l'image globale
 			// MAIS seulement sur cette petite zone
 			// AVANTAGE : Détecte les variations locales ignorées dans l'analyse globale
 			tileDesc := model.TileDescriptor{
-				HistogramRGB:     color.ComputeHistogramRGB(tileImg),       // Couleurs locales
-				HistogramHSV:     color.ComputeHistogramHSV(tileImg),       // HSV local
-				PHash:            hash.GeneratePHash(tileImg),              // Signature locale
-				MeanColor:        color.ComputeMeanColor(tileImg),          // Couleur dominante locale
-				TextureSignature: texture.ComputeTextureSignature(tileImg), // Rugosité locale
-				ShapeSignature:   shape.ComputeShapeSignature(tileImg),     // Contours locaux
+				HistogramRGB:     colors.rgb(tileImg),                                       // Couleurs locales
+				HistogramHSV:     colors.hsv(tileImg),                                       // HSV local
+				PHash:            model.PHash(hash.GeneratePHash(tileImg)),                  // Signature locale
+				MeanColor:        colors.mean(tileImg),                                      // Couleur dominante locale
+				TextureSignature: texture.ComputeTextureSignature(tileImg),                  // Rugosité locale
+				ShapeSignature:   shape.ComputeShapeSignature(tileImg),                      // Contours locaux
+				AlphaCoverage:    color.ComputeAlphaCoverage(normalized.SubImage(tileRect)), // Part opaque locale
 			}
 
 			// Ajout de cette tuile analysée à la collection
 			tiles = append(tiles, tileDesc)
 		}
 	}
+	return tiles
+}
 
-	// Construction de la structure finale qui contient TOUT
-	// ORGANISATION :
-	// - Métadonnées : nom de fichier
-	// - Niveau global : caractéristiques de l'image entière
-	// - Niveau local : 81 tuiles avec leurs caractéristiques individuelles
-	desc := &model.FullImageDescriptor{
-		ImageName:       filepath.Base(imagePath), // Nom du fichier seulement (sans chemin)
-		GlobalRGB:       globalRGB,                // Couleurs globales RGB
-		GlobalHSV:       globalHSV,                // Couleurs globales HSV
-		GlobalPHash:     globalPHash,              // Signature structurelle globale
-		GlobalMeanColor: globalMean,               // Teinte dominante globale
-		GlobalTexture:   globalTexture,            // Rugosité globale
-		GlobalShape:     globalShape,              // Richesse en formes globale
-		Tiles:           tiles,                    // Collection des 81 tuiles analysées
+/*
+===== RAMENÉE À LA TAILLE STANDARD =====
+
+À QUOI ÇA SERT :
+Redimensionne l'image décodée selon le mode de normalisation (voir
+model.NormalizeMode). Seul stretch déforme l'image ; les autres modes
+appliquent le même facteur d'échelle horizontalement et verticalement.
+
+EXEMPLE (image 512×256, taille standard 256) :
+- stretch : 256×256, image étirée verticalement
+- pad     : 256×256, image en 256×128 au centre, bandes en haut et en bas
+- crop    : 256×256, carré central 256×256 de l'image d'origine
+- fit     : 256×128
+
+En mode fit, le petit côté ne descend pas sous 3 pixels par tuile de la
+grille la plus fine (pyramide comprise).
+*/
+func normalizeImage(src image.Image, params model.AnalysisParams) *image.RGBA {
+	size := params.StandardSize
+	sb := src.Bounds()
+	w, h := sb.Dx(), sb.Dy()
+
+	// fitted : dimensions de l'image entière ramenée au carré sans déformation
+	fitted := func() (int, int) {
+		longest := max(w, h)
+		return max(1, int(math.Round(float64(w)*float64(size)/float64(longest)))),
+			max(1, int(math.Round(float64(h)*float64(size)/float64(longest))))
 	}
 
-	return desc, nil // Mission accomplie ! Descripteur complet prêt à l'emploi
+	switch params.Normalize {
+	case model.NormalizePad:
+		fw, fh := fitted()
+		dst := image.NewRGBA(image.Rect(0, 0, size, size))
+		x0, y0 := (size-fw)/2, (size-fh)/2
+		drawx.ApproxBiLinear.Scale(dst, image.Rect(x0, y0, x0+fw, y0+fh), src, sb, draw.Over, nil)
+		return dst
+
+	case model.NormalizeCrop:
+		side := min(w, h)
+		x0, y0 := sb.Min.X+(w-side)/2, sb.Min.Y+(h-side)/2
+		dst := image.NewRGBA(image.Rect(0, 0, size, size))
+		drawx.ApproxBiLinear.Scale(dst, dst.Bounds(), src, image.Rect(x0, y0, x0+side, y0+side), draw.Over, nil)
+		return dst
+
+	case model.NormalizeFit:
+		fw, fh := fitted()
+		minSide := 3 * params.MaxGrid()
+		dst := image.NewRGBA(image.Rect(0, 0, max(fw, minSide), max(fh, minSide)))
+		drawx.ApproxBiLinear.Scale(dst, dst.Bounds(), src, sb, draw.Over, nil)
+		return dst
+
+	default: // stretch
+		dst := image.NewRGBA(image.Rect(0, 0, size, size))
+		drawx.ApproxBiLinear.Scale(dst, dst.Bounds(), src, sb, draw.Over, nil)
+		return dst
+	}
+}
+
+/*
+===== TRAITEMENT DE LA TRANSPARENCE =====
+
+En mode background, retourne une copie de l'image posée sur la couleur de
+fond params.Background (plus aucun pixel transparent). Dans les autres
+modes, l'image est analysée telle quelle.
+*/
+func applyAlpha(img *image.RGBA, params model.AnalysisParams) (*image.RGBA, error) {
+	if params.Alpha != model.AlphaBackground {
+		return img, nil
+	}
+	bg, err := model.ParseBackground(params.Background)
+	if err != nil {
+		return nil, err
+	}
+	dst := image.NewRGBA(img.Bounds())
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(bg), image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), img, img.Bounds().Min, draw.Over)
+	return dst, nil
+}
+
+// colorFuncs : calculs de couleur adaptés au traitement de la transparence
+type colorFuncs struct {
+	rgb, hsv func(image.Image) map[string][]int
+	mean     func(image.Image) [3]float64
+}
+
+// colorFuncsFor : en mode ignore, les pixels totalement transparents sont exclus des couleurs
+func colorFuncsFor(mode model.AlphaMode) colorFuncs {
+	if mode == model.AlphaIgnore {
+		return colorFuncs{color.ComputeHistogramRGBVisible, color.ComputeHistogramHSVVisible, color.ComputeMeanColorVisible}
+	}
+	return colorFuncs{color.ComputeHistogramRGB, color.ComputeHistogramHSV, color.ComputeMeanColor}
+}
+
+// span : intervalle [start, end[ de pixels couvert par une tuile sur un axe
+type span struct {
+	start, end int
+}
+
+/*
+===== BORNES DES TUILES SUR UN AXE =====
+
+Découpe un axe de length pixels en n intervalles (n = TilesPerRow pour
+la grille de tuiles, n = grille du niveau pour la pyramide).
+
+En truncate, tuiles de length/n pixels (division entière) : le reste de
+l'axe n'appartient à aucune tuile.
+
+En cover, tuiles de côté T = length / (1 + (n-1)×stride) espacées de
+stride×T, bornes arrondies au pixel : la première commence à 0, la
+dernière finit à length. Avec stride = 1, l'axe est partagé exactement.
+*/
+func tileSpans(length, n int, params model.AnalysisParams) []span {
+	spans := make([]span, n)
+
+	if params.Tiling != model.TilingCover {
+		size := length / n
+		for i := range spans {
+			spans[i] = span{i * size, (i + 1) * size}
+		}
+		return spans
+	}
+
+	size := float64(length) / (1 + float64(n-1)*params.TileStride)
+	step := size * params.TileStride
+	for i := range spans {
+		start := float64(i) * step
+		spans[i] = span{int(math.Round(start)), int(math.Round(start + size))}
+	}
+	spans[n-1].end = length // Arrondis : la dernière tuile touche toujours le bord
+	return spans
 }
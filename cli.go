@@ -0,0 +1,116 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/MrIsmail1/Golang_images_matcher/config"
+	"github.com/MrIsmail1/Golang_images_matcher/model"
+)
+
+// Dossiers par défaut de la banque d'images et du cache des descripteurs
+const (
+	defaultBankDir  = "banque/images"
+	defaultCacheDir = "banque/json"
+)
+
+/*
+===== OPTIONS COMMUNES AUX SOUS-COMMANDES =====
+
+À QUOI ÇA SERT :
+Regroupe les flags partagés par index, search et inspect pour
+qu'ils aient partout le même nom et la même valeur par défaut.
+*/
+type commonFlags struct {
+	bankDir    string  // Dossier des images de la banque
+	cacheDir   string  // Dossier des descripteurs JSON (cache)
+	query      string  // Image(s) de requête, séparées par des virgules
+	index      string  // Index binaire mono-fichier (optionnel)
+	db         string  // Base clé-valeur bbolt (optionnel, remplace -cache)
+	normalize  string  // Mode de normalisation des images analysées
+	alpha      string  // Traitement des pixels transparents
+	background string  // Couleur de fond du mode -alpha background
+	tiling     string  // Découpage de la grille de tuiles
+	tileStride float64 // Pas entre tuiles voisines (fraction du côté d'une tuile)
+	pyramid    string  // Grilles des niveaux de la pyramide spatiale
+}
+
+// register déclare les flags communs sur le FlagSet de la sous-commande
+func (c *commonFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&c.bankDir, "bank", defaultBankDir, "dossier des images de la banque")
+	fs.StringVar(&c.cacheDir, "cache", defaultCacheDir, "dossier du cache des descripteurs JSON")
+	fs.StringVar(&c.query, "query", "", "image(s) de requête, séparées par des virgules")
+	fs.StringVar(&c.db, "db", "", "base de descripteurs bbolt (remplace le dossier -cache)")
+	fs.StringVar(&c.index, "index", "", "index binaire mono-fichier (remplace le dossier -cache comme banque)")
+	fs.StringVar(&c.normalize, "normalize", config.Normalize, "ramenée des images à la taille standard : stretch, pad, crop ou fit")
+	fs.StringVar(&c.alpha, "alpha", config.Alpha, "pixels transparents : black (comptés en noir), ignore (exclus des couleurs) ou background")
+	fs.StringVar(&c.background, "background", "#ffffff", "couleur de fond #rrggbb du mode -alpha background")
+	fs.StringVar(&c.tiling, "tiling", config.Tiling, "découpage des tuiles : truncate (bords ignorés) ou cover (toute l'image)")
+	fs.Float64Var(&c.tileStride, "tile-stride", 1, "pas entre tuiles voisines en fraction de leur côté, dans ]0, 1] (< 1 : tuiles chevauchantes, avec -tiling cover)")
+	fs.StringVar(&c.pyramid, "pyramid", "", "niveaux supplémentaires de la pyramide spatiale, grilles séparées par des virgules (ex. 2,4)")
+}
+
+/*
+===== PARAMÈTRES D'ANALYSE =====
+
+Paramètres de la configuration, avec le mode de normalisation -normalize
+le traitement de la transparence -alpha (et -background), le découpage
+des tuiles -tiling (et -tile-stride) et la pyramide spatiale -pyramid.
+Les descripteurs produits avec d'autres modes ne sont pas comparés.
+*/
+func (c *commonFlags) params() (model.AnalysisParams, error) {
+	params := model.CurrentParams()
+	params.Normalize = model.NormalizeMode(c.normalize)
+	params.Alpha = model.AlphaMode(c.alpha)
+	params.Tiling = model.TilingMode(c.tiling)
+	params.TileStride = c.tileStride
+	if params.Alpha == model.AlphaBackground {
+		bg, err := model.ParseBackground(c.background)
+		if err != nil {
+			return params, fmt.Errorf("-background : %w", err)
+		}
+		params.Background = model.FormatBackground(bg) // Forme canonique : #FFFFFF et #ffffff se comparent
+	}
+	grids, err := model.ParsePyramid(c.pyramid)
+	if err != nil {
+		return params, fmt.Errorf("-pyramid : %w", err)
+	}
+	params.Pyramid = model.FormatPyramid(grids) // Forme canonique : "2, 4" et "2,4" se comparent
+	if err := params.Validate(); err != nil {
+		return params, err
+	}
+	return params, nil
+}
+
+/*
+===== LISTE DES IMAGES DE REQUÊTE =====
+
+Combine le flag -query (liste séparée par des virgules) et les arguments
+positionnels restants, pour accepter aussi bien :
+
+	search -query a.png,b.png
+	search a.png b.png
+*/
+func (c *commonFlags) queries(fs *flag.FlagSet) []string {
+	var paths []string
+	for _, q := range strings.Split(c.query, ",") {
+		if q = strings.TrimSpace(q); q != "" {
+			paths = append(paths, q)
+		}
+	}
+	return append(paths, fs.Args()...)
+}
+
+/*
+===== OUVERTURE DU STOCKAGE DE DESCRIPTEURS =====
+
+Base bbolt si -db est fourni, sinon dossier de JSON (-cache).
+À fermer par l'appelant.
+*/
+func (c *commonFlags) openStore() (model.DescriptorStore, error) {
+	if c.db != "" {
+		return model.OpenBoltStore(c.db)
+	}
+	return model.NewJSONDirStore(c.cacheDir), nil
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+
+	"github.com/MrIsmail1/Golang_images_matcher/analyzer"
+	"github.com/MrIsmail1/Golang_images_matcher/compare"
+	"github.com/MrIsmail1/Golang_images_matcher/model"
+	"github.com/MrIsmail1/Golang_images_matcher/search"
+)
+
+/*
+===== SOUS-COMMANDE check =====
+
+À QUOI ÇA SERT :
+Vérifications de non-régression du score sur les images de la banque :
+1. Une image comparée à elle-même obtient exactement 100 %
+2. Une copie réencodée en JPEG arrive en tête face à toute la banque
+3. Une copie redimensionnée (-scale) arrive en tête face à toute la banque
+
+Les copies sont créées dans un dossier temporaire, supprimé à la fin.
+Toute vérification en échec fait sortir la commande en erreur.
+Outil optionnel : les mêmes vérifications sur banque/images avec les
+réglages par défaut font partie des tests (go test ./...). check sert à
+les rejouer sur une autre banque ou avec un fichier -scoring.
+*/
+func runCheck(args []string) error {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	var common commonFlags
+	common.register(fs)
+	scoringFile := fs.String("scoring", "", "fichier JSON des réglages du score à vérifier")
+	scale := fs.Float64("scale", 0.5, "facteur de redimensionnement des copies, dans ]0, 1]")
+	quality := fs.Int("quality", 75, "qualité JPEG des copies (1-100)")
+	fs.Parse(args)
+
+	if *scale <= 0 || *scale > 1 {
+		return fmt.Errorf("-scale doit être dans ]0, 1], reçu %g", *scale)
+	}
+
+	scoring := compare.DefaultScoring()
+	if *scoringFile != "" {
+		var err error
+		if scoring, err = compare.LoadScoringConfig(*scoringFile); err != nil {
+			return err
+		}
+	}
+
+	params, err := common.params()
+	if err != nil {
+		return err
+	}
+
+	store, err := common.openStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	// Descripteurs de toute la banque (cache ou analyse)
+	images, err := search.ListImages(common.bankDir)
+	if err != nil {
+		return err
+	}
+	if len(images) < 2 {
+		return fmt.Errorf("%s : au moins deux images nécessaires", common.bankDir)
+	}
+	cache := search.Cache{BankDir: common.bankDir, Store: store, Params: params}
+	descs := make([]*model.FullImageDescriptor, len(images))
+	for i, path := range images {
+		if descs[i], _, err = cache.Describe(path); err != nil {
+			return fmt.Errorf("%s : %w", path, err)
+		}
+	}
+	engine := search.NewEngine(descs)
+
+	tmpDir, err := os.MkdirTemp("", "gis-check-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	variants := []struct {
+		name  string
+		scale float64
+	}{
+		{"réencodée", 1},
+		{fmt.Sprintf("réduite ×%g", *scale), *scale},
+	}
+
+	failures := 0
+	fmt.Printf("%-24s  %9s  %s\n", "IMAGE", "SOI-MÊME", "RANG DES COPIES")
+	for i, path := range images {
+		self := compare.CompareDescriptorsWith(descs[i], descs[i], scoring)
+		status := "✅"
+		if math.Abs(self-100) > 1e-9 {
+			status = "❌"
+			failures++
+		}
+		line := fmt.Sprintf("%-24s  %s %6.2f%%", checkIdentity(descs[i]), status, self)
+
+		for j, v := range variants {
+			copyPath := filepath.Join(tmpDir, fmt.Sprintf("%d-%d.jpg", i, j))
+			if err := search.WriteJPEGCopy(path, copyPath, v.scale, *quality); err != nil {
+				return fmt.Errorf("%s : %w", path, err)
+			}
+			copyDesc, err := analyzer.AnalyzeImageWith(copyPath, params)
+			if err != nil {
+				return fmt.Errorf("%s : %w", copyPath, err)
+			}
+
+			// IncludeSelf : la copie ne doit jamais être confondue avec une image de la banque
+			res, err := engine.Query(copyDesc, search.Options{IncludeSelf: true, Scoring: &scoring})
+			if err != nil {
+				return err
+			}
+			rank := search.OriginalRank(res.Matches, descs[i])
+			status := "✅"
+			if rank != 1 {
+				status = "❌"
+				failures++
+			}
+			line += fmt.Sprintf("  %s %s : %d/%d", status, v.name, rank, len(res.Matches))
+		}
+		fmt.Println(line)
+	}
+
+	if failures > 0 {
+		return fmt.Errorf("%d vérification(s) en échec", failures)
+	}
+	fmt.Printf("✅ %d image(s) vérifiée(s)\n", len(images))
+	return nil
+}
+
+// checkIdentity : chemin relatif à la banque, sinon nom de l'image (comme search.Match)
+func checkIdentity(desc *model.FullImageDescriptor) string {
+	if desc.Source != nil && desc.Source.Path != "" {
+		return desc.Source.Path
+	}
+	return desc.ImageName
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/MrIsmail1/Golang_images_matcher/model"
+	"github.com/MrIsmail1/Golang_images_matcher/search"
+)
+
+/*
+===== SOUS-COMMANDE convert =====
+
+À QUOI ÇA SERT :
+Convertit la banque de descripteurs entre les formats de stockage :
+- json : dossier de fichiers JSON (-cache)
+- bin  : index binaire mono-fichier (-index)
+- db   : base clé-valeur bbolt (-db)
+
+EXEMPLES :
+
+	convert -from json -to bin -cache banque/json -index banque/index.bin
+	convert -from bin -to json -index banque/index.bin -cache banque/json
+	convert -from json -to db -cache banque/json -db banque/descripteurs.db
+
+Les descripteurs illisibles ou incompatibles sont signalés et ignorés.
+Un index binaire ne contient que des descripteurs produits avec le mode
+de normalisation -normalize.
+Un descripteur à la fois : la banque n'est jamais entièrement en mémoire.
+*/
+func runConvert(args []string) error {
+	fs := flag.NewFlagSet("convert", flag.ExitOnError)
+	var common commonFlags
+	common.register(fs)
+	from := fs.String("from", "json", "format source : json, bin ou db")
+	to := fs.String("to", "bin", "format de destination : json, bin ou db")
+	fs.Parse(args)
+
+	if *from == *to {
+		return fmt.Errorf("formats source et destination identiques : %s", *from)
+	}
+	if (*from == "bin" || *to == "bin") && common.index == "" {
+		return fmt.Errorf("chemin de l'index binaire requis (utilisez -index)")
+	}
+	if (*from == "db" || *to == "db") && common.db == "" {
+		return fmt.Errorf("chemin de la base requis (utilisez -db)")
+	}
+
+	// Destination : fonction appelée pour chaque descripteur, puis finalisation
+	add, finish, err := openSink(*to, &common)
+	if err != nil {
+		return err
+	}
+
+	written := 0
+	err = readSource(*from, &common, func(key string, desc *model.FullImageDescriptor) {
+		if err := add(key, desc); err != nil {
+			fmt.Println("⚠️  Ignoré :", err)
+			return
+		}
+		written++
+	})
+	if ferr := finish(err == nil); err == nil {
+		err = ferr
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("📦 %d descripteur(s) convertis (%s → %s)\n", written, *from, *to)
+	return nil
+}
+
+/*
+===== LECTURE DE LA SOURCE =====
+
+Appelle fn pour chaque descripteur lisible ; les autres sont signalés.
+Les descripteurs des images de requête hors banque sont écartés : ils
+deviendraient sinon des candidats dans la destination.
+*/
+func readSource(format string, common *commonFlags, fn func(string, *model.FullImageDescriptor)) error {
+	if format == "bin" {
+		file, err := os.Open(common.index)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		return model.ReadBinaryIndex(file, func(desc *model.FullImageDescriptor) error {
+			if search.IsExternalSource(desc.Source) {
+				return nil // Requête hors banque : pas un descripteur de la banque
+			}
+			fn(descriptorKey(desc), desc)
+			return nil
+		})
+	}
+
+	var store model.DescriptorStore
+	switch format {
+	case "json":
+		store = model.NewJSONDirStore(common.cacheDir)
+	case "db":
+		db, err := model.OpenBoltStore(common.db)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		store = db
+	default:
+		return fmt.Errorf("format source inconnu : %q (json, bin ou db)", format)
+	}
+
+	keys, err := store.Keys()
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if search.IsExternalKey(key) {
+			continue // Requêtes hors banque (_externes) : pas des descripteurs de la banque
+		}
+		desc, err := store.Get(key)
+		if err != nil {
+			fmt.Println("⚠️  Ignoré :", err)
+			continue
+		}
+		fn(key, desc)
+	}
+	return nil
+}
+
+/*
+===== OUVERTURE DE LA DESTINATION =====
+
+Retourne la fonction d'ajout et la fonction de finalisation (appelée avec
+ok = false si la lecture a échoué : l'index binaire partiel est alors abandonné).
+*/
+func openSink(format string, common *commonFlags) (func(string, *model.FullImageDescriptor) error, func(ok bool) error, error) {
+	switch format {
+	case "json":
+		store := model.NewJSONDirStore(common.cacheDir)
+		return store.Put, func(bool) error { return nil }, nil
+
+	case "db":
+		store, err := model.OpenBoltStore(common.db)
+		if err != nil {
+			return nil, nil, err
+		}
+		return store.Put, func(bool) error { return store.Close() }, nil
+
+	case "bin":
+		// Écriture dans un fichier temporaire renommé à la fin :
+		// l'index n'apparaît qu'une fois complet
+		tmp, err := os.CreateTemp(filepath.Dir(common.index), ".binindex-*")
+		if err != nil {
+			return nil, nil, err
+		}
+		var w *model.BinaryIndexWriter
+		params, err := common.params()
+		if err == nil {
+			w, err = model.NewBinaryIndexWriter(tmp, params)
+		}
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+			return nil, nil, err
+		}
+
+		add := func(_ string, desc *model.FullImageDescriptor) error { return w.Add(desc) }
+		finish := func(ok bool) error {
+			defer os.Remove(tmp.Name()) // Sans effet après le renommage
+			if !ok {
+				tmp.Close()
+				return nil
+			}
+			// Données physiquement écrites avant de publier l'index
+			err := errors.Join(w.Close(), tmp.Chmod(0o644), tmp.Sync(), tmp.Close())
+			if err != nil {
+				return err
+			}
+			return os.Rename(tmp.Name(), common.index)
+		}
+		return add, finish, nil
+
+	default:
+		return nil, nil, fmt.Errorf("format de destination inconnu : %q (json, bin ou db)", format)
+	}
+}
+
+/*
+===== CLÉ D'UN DESCRIPTEUR LU DANS L'INDEX BINAIRE =====
+
+Même clé que le cache : chemin relatif à la banque, sinon nom de l'image.
+*/
+func descriptorKey(desc *model.FullImageDescriptor) string {
+	if desc.Source != nil && desc.Source.Path != "" && !filepath.IsAbs(desc.Source.Path) {
+		return desc.Source.Path
+	}
+	return desc.ImageName
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/MrIsmail1/Golang_images_matcher/search"
+)
+
+/*
+===== SOUS-COMMANDE index =====
+
+À QUOI ÇA SERT :
+Construit (ou complète) le cache des descripteurs pour toutes les images
+du dossier de la banque, en parallèle sur -workers goroutines.
+Les images dont le descripteur est à jour sont ignorées, sauf avec -force.
+*/
+func runIndex(args []string) error {
+	fs := flag.NewFlagSet("index", flag.ExitOnError)
+	var common commonFlags
+	common.register(fs)
+	force := fs.Bool("force", false, "régénère les descripteurs même s'ils sont à jour")
+	workers := fs.Int("workers", 0, "nombre d'analyses simultanées (0 = nombre de CPU)")
+	fs.Parse(args)
+
+	params, err := common.params()
+	if err != nil {
+		return err
+	}
+
+	store, err := common.openStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	report, err := search.IndexDirectory(common.bankDir, store, search.IndexOptions{
+		Workers: *workers,
+		Force:   *force,
+		Params:  params,
+		Progress: func(p search.IndexProgress) {
+			if p.Err != nil {
+				fmt.Printf("[%d/%d] ⚠️  %s : %v\n", p.Done, p.Total, p.Path, p.Err)
+				return
+			}
+			fmt.Printf("[%d/%d] %s : %s\n", p.Done, p.Total, p.Path, p.Status)
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("\n📦 %d image(s) : %d généré(s), %d à jour, %d erreur(s)\n",
+		report.Total, report.Generated, report.UpToDate, len(report.Errors))
+	if len(report.Errors) > 0 {
+		return fmt.Errorf("%d image(s) n'ont pas pu être indexées", len(report.Errors))
+	}
+	return nil
+}
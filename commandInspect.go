@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/MrIsmail1/Golang_images_matcher/model"
+	"github.com/MrIsmail1/Golang_images_matcher/search"
+)
+
+/*
+===== SOUS-COMMANDE inspect =====
+
+À QUOI ÇA SERT :
+Affiche le descripteur d'une image : un résumé lisible par défaut,
+ou le JSON complet avec -json. Le descripteur vient soit d'un fichier
+(-descriptor), soit du cache/de l'analyse d'une image (-query).
+*/
+func runInspect(args []string) error {
+	fs := flag.NewFlagSet("inspect", flag.ExitOnError)
+	var common commonFlags
+	common.register(fs)
+	descriptorPath := fs.String("descriptor", "", "fichier JSON de descripteur à afficher")
+	dumpJSON := fs.Bool("json", false, "affiche le descripteur JSON complet")
+	fs.Parse(args)
+
+	var desc *model.FullImageDescriptor
+	source := *descriptorPath
+
+	if source != "" {
+		var err error
+		if desc, err = model.LoadDescriptor(source); err != nil {
+			return err
+		}
+	} else {
+		queries := common.queries(fs)
+		if len(queries) != 1 {
+			return fmt.Errorf("une seule image attendue (utilisez -query ou -descriptor)")
+		}
+		params, err := common.params()
+		if err != nil {
+			return err
+		}
+		store, err := common.openStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+
+		cache := search.Cache{BankDir: common.bankDir, Store: store, Params: params}
+		if desc, source, err = cache.Describe(queries[0]); err != nil {
+			return err
+		}
+	}
+
+	if *dumpJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(desc)
+	}
+
+	// Résumé des caractéristiques globales
+	fmt.Println("📄 Descripteur    :", source)
+	fmt.Println("🖼️  Image          :", desc.ImageName)
+	alpha := string(desc.Params.Alpha)
+	if desc.Params.Background != "" {
+		alpha += " " + desc.Params.Background
+	}
+	pyramid := ""
+	if desc.Params.Pyramid != "" {
+		pyramid = ", pyramide " + desc.Params.Pyramid
+	}
+	fmt.Printf("📐 Paramètres      : %d px, %d bins, grille %d×%d (%s, pas %g)%s, %s, transparence %s\n",
+		desc.Params.StandardSize, desc.Params.Bins, desc.Params.TilesPerRow, desc.Params.TilesPerRow,
+		desc.Params.Tiling, desc.Params.TileStride, pyramid, desc.Params.Normalize, alpha)
+	fmt.Println("🔢 pHash global   :", desc.GlobalPHash)
+	fmt.Printf("🎨 Couleur moyenne : [%.1f, %.1f, %.1f]\n", desc.GlobalMeanColor[0], desc.GlobalMeanColor[1], desc.GlobalMeanColor[2])
+	fmt.Printf("🌫️  Texture         : %.3f\n", desc.GlobalTexture)
+	fmt.Printf("🔺 Forme           : %.3f\n", desc.GlobalShape)
+	fmt.Printf("🫥 Couverture alpha : %.3f\n", desc.GlobalAlphaCoverage)
+	fmt.Println("🧩 Tuiles          :", len(desc.Tiles))
+	for _, level := range desc.Levels {
+		fmt.Printf("🔭 %-16s: %d tuiles\n", fmt.Sprintf("Niveau %d×%d", level.Grid, level.Grid), len(level.Tiles))
+	}
+	return nil
+}
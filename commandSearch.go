@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+
+	"github.com/MrIsmail1/Golang_images_matcher/compare"
+	"github.com/MrIsmail1/Golang_images_matcher/index"
+	"github.com/MrIsmail1/Golang_images_matcher/search"
+)
+
+/*
+===== SOUS-COMMANDE search =====
+
+À QUOI ÇA SERT :
+Compare chaque image de requête à tous les descripteurs du cache
+et affiche le classement des K meilleures correspondances (-top).
+La recherche peut être bornée dans le temps (-timeout) ou interrompue (Ctrl-C).
+Sur les grosses banques, -phash-k et -phash-radius restreignent la
+comparaison complète aux images de pHash global proche, et -ann-k aux
+images les plus proches dans l'index vectoriel (HNSW, fichier -ann).
+Toute la logique de recherche est dans le package search.
+*/
+func runSearch(args []string) error {
+	fs := flag.NewFlagSet("search", flag.ExitOnError)
+	var common commonFlags
+	common.register(fs)
+	top := fs.Int("top", 5, "nombre de résultats à afficher (0 = tous)")
+	workers := fs.Int("workers", 0, "nombre de goroutines de comparaison (0 = nombre de CPU)")
+	timeout := fs.Duration("timeout", 0, "durée maximale de la recherche, ex. 30s (0 = illimitée)")
+	phashK := fs.Int("phash-k", 0, "ne compare que les N images au pHash global le plus proche (0 = toute la banque)")
+	phashRadius := fs.Int("phash-radius", 0, "ne compare que les images à distance de Hamming ≤ R du pHash global (0 = sans limite)")
+	annK := fs.Int("ann-k", 0, "ne compare que les N images les plus proches dans l'index vectoriel (0 = toute la banque)")
+	annFile := fs.String("ann", "", "fichier de l'index vectoriel : chargé et mis à jour s'il existe, sinon construit et enregistré")
+	scoringFile := fs.String("scoring", "", "fichier JSON des réglages du score (poids, seuils), voir compare.ScoringConfig")
+	explain := fs.Bool("explain", false, "détaille le score de chaque résultat (distances, score global, grille des tuiles)")
+	annTiles := fs.Bool("ann-tiles", false, "inclut le résumé des tuiles dans les vecteurs (à la construction de l'index)")
+	fs.Parse(args)
+
+	// Ctrl-C annule proprement la recherche en cours
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+
+	queries := common.queries(fs)
+	if len(queries) == 0 {
+		return fmt.Errorf("aucune image de requête (utilisez -query)")
+	}
+
+	// Réglages du score : par défaut, ou fichier de configuration
+	scoring := compare.DefaultScoring()
+	if *scoringFile != "" {
+		var err error
+		if scoring, err = compare.LoadScoringConfig(*scoringFile); err != nil {
+			return err
+		}
+	}
+
+	params, err := common.params()
+	if err != nil {
+		return err
+	}
+
+	// Stockage des descripteurs : base bbolt (-db) ou dossier de JSON (-cache)
+	store, err := common.openStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	// Banque : index binaire s'il est fourni, sinon le stockage lui-même
+	var engine *search.Engine
+	cache := search.Cache{BankDir: common.bankDir, Store: store, Params: params}
+	if common.index != "" {
+		engine, err = search.NewEngineFromBinaryIndex(common.index, cache)
+	} else {
+		engine, err = search.NewEngineFromCache(cache)
+	}
+	if err != nil {
+		return err
+	}
+	defer engine.Close()
+
+	if *annK > 0 {
+		if err := prepareVectorIndex(engine, *annFile, *annTiles); err != nil {
+			return err
+		}
+	}
+
+	for _, imagePath := range queries {
+		opts := search.Options{
+			TopK:             *top,
+			Workers:          *workers,
+			PHashCandidates:  *phashK,
+			PHashRadius:      *phashRadius,
+			VectorCandidates: *annK,
+			Scoring:          &scoring,
+			Explain:          *explain,
+		}
+		res, err := engine.QueryImageContext(ctx, imagePath, opts)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("\n🔍 Requête : %s\n", imagePath)
+		for _, skipped := range res.Skipped {
+			fmt.Println("⚠️  Descripteur ignoré :", skipped.Err)
+		}
+
+		if len(res.Matches) == 0 {
+			fmt.Println("❌ Aucune correspondance trouvée.")
+			continue
+		}
+		printMatches(res.Matches)
+		if *explain {
+			for i, m := range res.Matches {
+				printBreakdown(i+1, m)
+			}
+		}
+	}
+	return nil
+}
+
+/*
+===== DÉTAIL D'UN SCORE (-explain) =====
+
+Distances normalisées des caractéristiques globales (0 = identiques),
+scores intermédiaires, puis la grille des scores de tuiles en pourcentage
+(- : tuile sans correspondante), le nombre de tuiles appariées à une autre
+position (hors mode aligned) et le score de chaque niveau de la pyramide.
+*/
+func printBreakdown(rank int, m search.Match) {
+	b := m.Breakdown
+	if b == nil {
+		return
+	}
+
+	fmt.Printf("\n📋 #%d %s : %.2f%%\n", rank, m.ImageName, b.Score)
+	g := b.Global
+	fmt.Printf("   Distances globales : RGB %.3f  HSV %.3f  couleur %.3f  texture %.3f  forme %.3f  pHash %.3f  alpha %.3f\n",
+		g.RGB, g.HSV, g.Color, g.Texture, g.Shape, g.PHash, g.Alpha)
+	fmt.Printf("   Score global : %.2f%%   Score des tuiles : %.2f%%\n", b.GlobalScore*100, b.TileScore*100)
+	fmt.Println("   Tuiles :")
+	for y, row := range b.Tiles {
+		fmt.Print("  ")
+		for x, score := range row {
+			if b.TileMatches != nil && b.TileMatches[y*len(row)+x] < 0 {
+				fmt.Print("     -") // Tuile sans correspondante (mode shift)
+				continue
+			}
+			fmt.Printf(" %5.1f", score*100)
+		}
+		fmt.Println()
+	}
+	if b.TileMatches != nil {
+		fmt.Printf("   Tuiles appariées ailleurs qu'à leur position : %d/%d\n", movedTiles(b.TileMatches), len(b.TileMatches))
+	}
+	for _, level := range b.Levels {
+		fmt.Printf("   Niveau %d×%d : %.2f%%\n", level.Grid, level.Grid, level.Score*100)
+	}
+}
+
+// movedTiles compte les tuiles appariées à une tuile d'une autre position (-1 : sans correspondante)
+func movedTiles(matches []int) int {
+	moved := 0
+	for i, j := range matches {
+		if j >= 0 && j != i {
+			moved++
+		}
+	}
+	return moved
+}
+
+/*
+===== PRÉPARATION DE L'INDEX VECTORIEL =====
+
+Charge l'index du fichier s'il existe (mis à jour avec la banque actuelle),
+sinon le construit. Le fichier est réécrit si l'index a changé.
+*/
+func prepareVectorIndex(engine *search.Engine, path string, tiles bool) error {
+	if path == "" {
+		engine.BuildVectorIndex(search.VectorIndexOptions{Vector: index.VectorOptions{Tiles: tiles}})
+		return nil
+	}
+
+	f, err := os.Open(path)
+	switch {
+	case err == nil:
+		err = engine.LoadVectorIndex(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%s : %w", path, err)
+		}
+	case errors.Is(err, os.ErrNotExist):
+		engine.BuildVectorIndex(search.VectorIndexOptions{Vector: index.VectorOptions{Tiles: tiles}})
+	default:
+		return err
+	}
+
+	if !engine.VectorIndexModified() {
+		return nil
+	}
+
+	// Écriture dans un fichier temporaire puis renommage : jamais d'index tronqué
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".vecindex-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // Sans effet après le renommage
+	// Données physiquement écrites avant de publier l'index
+	if err := errors.Join(engine.SaveVectorIndex(tmp), tmp.Chmod(0o644), tmp.Sync(), tmp.Close()); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+/*
+===== AFFICHAGE DU CLASSEMENT =====
+
+Tableau aligné : rang, score, image et descripteur.
+La première ligne est la meilleure correspondance.
+*/
+func printMatches(matches []search.Match) {
+	fmt.Printf("%4s  %8s  %-24s  %s\n", "RANG", "SCORE", "IMAGE", "DESCRIPTEUR")
+	for i, m := range matches {
+		image := m.SourcePath
+		if image == "" {
+			image = m.ImageName // Anciens descripteurs sans chemin source
+		}
+		fmt.Printf("%4d  %7.2f%%  %-24s  %s\n", i+1, m.Score, image, m.DescriptorPath)
+	}
+}
@@ -1,6 +1,10 @@
 package compare_utils
 
-import "fmt"
+import (
+	"fmt"
+	"math/bits"
+	"strconv"
+)
 
 /*
 ===== DISTANCE DE HAMMING ENTRE DEUX pHash =====
@@ -14,34 +18,39 @@ PRINCIPE DE LA DISTANCE DE HAMMING :
 - Compte combien de positions ont des bits différents
 - Résultat = nombre total de différences
 
+OPTIMISATION :
+Chemin critique (82 pHash par comparaison d'images) : un XOR puis
+bits.OnesCount64, compilé en une seule instruction POPCNT sur les
+processeurs qui la proposent.
+
 Paramètres :
-- hash1, hash2 : hashes sous forme hexadécimale (ex: "a1b2c3d4e5f67890")
+- hash1, hash2 : hashes sous forme d'entiers 64 bits
 
 Retour :
 - Nombre de bits différents (0-64)
 */
-func HammingDistance(hash1, hash2 string) int {
-
-	var v1, v2 uint64
+func HammingDistance(hash1, hash2 uint64) int {
 
-	// Conversion des chaînes hexadécimales vers entiers 64 bits
-	// FONCTION : fmt.Sscanf avec format "%x" pour hexadécimal
-	fmt.Sscanf(hash1, "%x", &v1) // Premier hash → entier v1
-	fmt.Sscanf(hash2, "%x", &v2) // Deuxième hash → entier v2
-
-	// XOR binaire pour marquer les positions où les hashes diffèrent
-	xor := v1 ^ v2
+	// XOR binaire pour marquer les positions où les hashes diffèrent,
+	// puis comptage des bits à 1
+	return bits.OnesCount64(hash1 ^ hash2)
+}
 
-	dist := 0 // Compteur de bits différents
+/*
+===== DISTANCE DE HAMMING ENTRE DEUX pHash HEXADÉCIMAUX =====
 
-	// Boucle optimisée de comptage (algorithme de Brian Kernighan)
-	// PRINCIPE : À chaque itération, on élimine le bit le plus à droite
-	// OPÉRATION MAGIQUE : xor &= (xor - 1)
-	//
-	for xor != 0 {
-		dist++         // Un bit différent de plus trouvé
-		xor &= xor - 1 // Élimine le bit le plus à droite (technique de Kernighan)
+Variante pour des hashes sous forme de chaînes (ex: "a1b2c3d4e5f67890").
+Retourne une erreur si une chaîne n'est pas un hexadécimal valide
+de 64 bits au plus, au lieu de la traiter comme 0.
+*/
+func HammingDistanceHex(hash1, hash2 string) (int, error) {
+	v1, err := strconv.ParseUint(hash1, 16, 64)
+	if err != nil {
+		return 0, fmt.Errorf("pHash %q invalide : %w", hash1, err)
 	}
-
-	return dist // Retour du nombre total de bits différents
+	v2, err := strconv.ParseUint(hash2, 16, 64)
+	if err != nil {
+		return 0, fmt.Errorf("pHash %q invalide : %w", hash2, err)
+	}
+	return HammingDistance(v1, v2), nil
 }
@@ -0,0 +1,202 @@
+package compare_utils
+
+import (
+	"fmt"
+	"math"
+)
+
+/*
+===== MÉTRIQUES DE DISTANCE ENTRE HISTOGRAMMES =====
+
+À QUOI ÇA SERT :
+CompareHistograms additionne des différences de COMPTEURS bruts : une tuile
+de 28×28 pixels et l'image de 256×256 ne sont pas à la même échelle.
+Les métriques ci-dessous comparent des histogrammes NORMALISÉS (somme 1,
+c'est-à-dire des proportions de pixels) et retournent toutes une distance
+dans [0, 1] : 0 = distributions identiques, 1 = aucun recouvrement.
+
+CHOIX DE LA MÉTRIQUE :
+- l1            : demi-somme des écarts de proportions (variation totale)
+- intersection  : 1 - Σ min(p, q), part de pixels non communs (= l1 une fois normalisé)
+- chi2          : ½ Σ (p-q)² / (p+q), sensible aux petits bins
+- bhattacharyya : 1 - Σ √(p·q), 1 - coefficient de Bhattacharyya
+- hellinger     : √(1 - Σ √(p·q)), vraie distance, robuste au bruit
+- cosine        : 1 - cos(p, q)
+- js            : divergence de Jensen-Shannon (log base 2)
+- emd           : Earth Mover's Distance 1D, tolère un glissement vers un bin voisin
+- raw-l1        : calcul historique (CompareHistograms sur les compteurs)
+*/
+type HistogramMetric string
+
+const (
+	MetricRawL1         HistogramMetric = "raw-l1"
+	MetricL1            HistogramMetric = "l1"
+	MetricIntersection  HistogramMetric = "intersection"
+	MetricChiSquare     HistogramMetric = "chi2"
+	MetricBhattacharyya HistogramMetric = "bhattacharyya"
+	MetricHellinger     HistogramMetric = "hellinger"
+	MetricCosine        HistogramMetric = "cosine"
+	MetricJensenShannon HistogramMetric = "js"
+	MetricEMD           HistogramMetric = "emd"
+)
+
+// HistogramMetrics liste les métriques disponibles
+var HistogramMetrics = []HistogramMetric{
+	MetricRawL1, MetricL1, MetricIntersection, MetricChiSquare, MetricBhattacharyya,
+	MetricHellinger, MetricCosine, MetricJensenShannon, MetricEMD,
+}
+
+// Valid indique si la métrique est connue
+func (m HistogramMetric) Valid() bool {
+	for _, known := range HistogramMetrics {
+		if m == known {
+			return true
+		}
+	}
+	return false
+}
+
+// histogramTotal : nombre de pixels comptés dans l'histogramme
+func histogramTotal(hist []int) float64 {
+	total := 0
+	for _, v := range hist {
+		total += v
+	}
+	return float64(total)
+}
+
+/*
+===== DISTANCE ENTRE DEUX HISTOGRAMMES D'UNE COMPOSANTE =====
+
+Normalise à la volée (sans allocation, chemin critique des tuiles) puis
+applique la métrique. Deux histogrammes vides sont identiques (0), un seul
+histogramme vide est à distance maximale (1).
+Retourne une erreur pour une métrique inconnue ou raw-l1 (qui n'est pas
+normalisée : voir CompareHistograms).
+*/
+func HistogramDistance(h1, h2 []int, metric HistogramMetric) (float64, error) {
+	if len(h1) != len(h2) {
+		return 0, fmt.Errorf("histogrammes de %d et %d bins", len(h1), len(h2))
+	}
+	t1, t2 := histogramTotal(h1), histogramTotal(h2)
+	switch {
+	case t1 == 0 && t2 == 0:
+		return 0, nil
+	case t1 == 0 || t2 == 0:
+		return 1, nil
+	}
+
+	// Proportion du bin i dans chaque histogramme
+	p := func(i int) float64 { return float64(h1[i]) / t1 }
+	q := func(i int) float64 { return float64(h2[i]) / t2 }
+
+	var d float64
+	switch metric {
+	case MetricL1:
+		for i := range h1 {
+			d += math.Abs(p(i) - q(i))
+		}
+		d /= 2
+
+	case MetricIntersection:
+		common := 0.0
+		for i := range h1 {
+			common += math.Min(p(i), q(i))
+		}
+		d = 1 - common
+
+	case MetricChiSquare:
+		for i := range h1 {
+			if s := p(i) + q(i); s > 0 {
+				diff := p(i) - q(i)
+				d += diff * diff / s
+			}
+		}
+		d /= 2
+
+	case MetricBhattacharyya, MetricHellinger:
+		bc := 0.0
+		for i := range h1 {
+			bc += math.Sqrt(p(i) * q(i))
+		}
+		d = math.Max(0, 1-bc) // Arrondis : bc peut dépasser 1 de quelques ulp
+		if metric == MetricHellinger {
+			d = math.Sqrt(d)
+		}
+
+	case MetricCosine:
+		var dot, n1, n2 float64
+		for i := range h1 {
+			dot += p(i) * q(i)
+			n1 += p(i) * p(i)
+			n2 += q(i) * q(i)
+		}
+		d = math.Max(0, 1-dot/math.Sqrt(n1*n2))
+
+	case MetricJensenShannon:
+		// JS = ½ KL(p‖m) + ½ KL(q‖m), avec m la moyenne des deux distributions
+		for i := range h1 {
+			pi, qi := p(i), q(i)
+			m := (pi + qi) / 2
+			if pi > 0 {
+				d += pi * math.Log2(pi/m) / 2
+			}
+			if qi > 0 {
+				d += qi * math.Log2(qi/m) / 2
+			}
+		}
+
+	case MetricEMD:
+		// En 1D, le coût de transport optimal est la somme des écarts entre cumulés
+		if len(h1) < 2 {
+			return 0, nil
+		}
+		var c1, c2 float64
+		for i := range h1 {
+			c1 += p(i)
+			c2 += q(i)
+			d += math.Abs(c1 - c2)
+		}
+		d /= float64(len(h1) - 1) // Tout déplacer d'un bout à l'autre = 1
+
+	default:
+		return 0, fmt.Errorf("métrique d'histogramme %q non normalisée ou inconnue", metric)
+	}
+	return math.Min(math.Max(d, 0), 1), nil
+}
+
+// histogramKeys : composantes des histogrammes RGB puis HSV, dans l'ordre de parcours
+var histogramKeys = [...]string{"r", "g", "b", "h", "s", "v"}
+
+/*
+===== COMPARAISON D'HISTOGRAMMES MULTI-COMPOSANTES =====
+
+Moyenne des distances de chaque composante (r, g, b ou h, s, v),
+dans [0, 1] quelle que soit la taille des images comparées.
+Les composantes sont parcourues dans l'ordre fixe de histogramKeys, sans
+tri à chaque appel : la somme flottante, donc le classement, est identique
+d'une exécution à l'autre. Une autre composante est une erreur.
+*/
+func CompareHistogramsWith(h1, h2 map[string][]int, metric HistogramMetric) (float64, error) {
+	if len(h1) == 0 {
+		return 0, nil
+	}
+
+	total, n := 0.0, 0
+	for _, key := range histogramKeys {
+		hist, ok := h1[key]
+		if !ok {
+			continue
+		}
+		d, err := HistogramDistance(hist, h2[key], metric)
+		if err != nil {
+			return 0, fmt.Errorf("composante %s : %w", key, err)
+		}
+		total += d
+		n++
+	}
+	if n != len(h1) {
+		return 0, fmt.Errorf("composantes d'histogramme inconnues, %v attendues", histogramKeys)
+	}
+	return total / float64(n), nil
+}
@@ -14,74 +14,251 @@ Cette fonction évalue la similarité entre deux images, en comparant :
 - leurs caractéristiques globales (histogrammes, couleurs moyennes, textures, hash perceptuel),
 - ainsi que les caractéristiques détaillées de leurs tuiles.
 
+Utilise les réglages par défaut (DefaultScoring), voir CompareDescriptorsWith.
+
 Retour :
 - Score de similarité final entre 0 et 100 (%)
 */
 func CompareDescriptors(desc1, desc2 *model.FullImageDescriptor) float64 {
+	return CompareDescriptorsWith(desc1, desc2, DefaultScoring())
+}
+
+/*
+===== COMPARAISON AVEC DES RÉGLAGES DONNÉS =====
+
+Même calcul que CompareDescriptors, avec les poids, normalisations, métrique
+d'histogramme, seuil et appariement des tuiles et répartition
+global/tuiles/pyramide de cfg.
+Les champs de cfg que ScoringConfig.Validate refuserait (métrique inconnue,
+échelle de texture nulle...) reprennent leur valeur par défaut, et la
+valeur zéro ScoringConfig{} vaut DefaultScoring().
+*/
+func CompareDescriptorsWith(desc1, desc2 *model.FullImageDescriptor, cfg ScoringConfig) float64 {
+	return compareDescriptors(desc1, desc2, cfg, nil)
+}
+
+/*
+===== DÉTAIL D'UNE COMPARAISON =====
+
+À QUOI ÇA SERT :
+Explique un score : distances normalisées de chaque caractéristique globale,
+score global, score moyen des tuiles, score de chaque tuile, scores des
+niveaux de la pyramide et score final.
+Plus coûteux que CompareDescriptorsWith (grille allouée à chaque appel) :
+à réserver à l'affichage des meilleurs résultats.
+*/
+func ExplainDescriptors(desc1, desc2 *model.FullImageDescriptor, cfg ScoringConfig) *Breakdown {
+	b := &Breakdown{}
+	compareDescriptors(desc1, desc2, cfg, b)
+	return b
+}
+
+/*
+===== CALCUL DU SCORE =====
+
+Cœur commun de CompareDescriptorsWith et ExplainDescriptors :
+b reçoit le détail du calcul s'il n'est pas nil.
+*/
+func compareDescriptors(desc1, desc2 *model.FullImageDescriptor, cfg ScoringConfig, b *Breakdown) float64 {
+	cfg = cfg.usable() // Réglages non validés par l'appelant : jamais de panique ni de NaN
+
 	// --- Comparaison globale ---
-	rgbDist := compare_utils.CompareHistograms(desc1.GlobalRGB, desc2.GlobalRGB)               // Distance des histogrammes RGB
-	hsvDist := compare_utils.CompareHistograms(desc1.GlobalHSV, desc2.GlobalHSV)               // Distance des histogrammes HSV
-	colorDist := compare_utils.EuclideanDistance(desc1.GlobalMeanColor, desc2.GlobalMeanColor) // Distance euclidienne des moyennes de couleurs
-	textureDist := math.Abs(desc1.GlobalTexture - desc2.GlobalTexture)                         // Différence absolue de texture
-	phashDist := compare_utils.HammingDistance(desc1.GlobalPHash, desc2.GlobalPHash)           // Distance de Hamming entre les pHash
-
-	// --- Normalisation des distances ---
-	normRGB := rgbDist / float64(config.Bins*3*255)
-	normHSV := hsvDist / float64(config.Bins*3*255)
-	normColor := colorDist / (255 * math.Sqrt(3))
-	normTexture := textureDist / 500.0
-	normPHash := float64(phashDist) / 64.0
-
-	shapeDist := math.Abs(desc1.GlobalShape - desc2.GlobalShape)
-	normShape := shapeDist / 1.0 // car ratio ∈ [0,1]
-
-	globalScore := 1 -
-		normRGB*0.1 -
-		normHSV*0.1 -
-		normColor*0.15 -
-		normTexture*0.15 -
-		normShape*0.25 -
-		(1-normPHash)*0.25
-
-	// --- Comparaison tuile par tuile ---
-	var tileScoreSum float64
-	for i := 0; i < len(desc1.Tiles); i++ {
-		t1 := desc1.Tiles[i]
-		t2 := desc2.Tiles[i]
-
-		// Comparaison locale
-		rgbDist := compare_utils.CompareHistograms(t1.HistogramRGB, t2.HistogramRGB)
-		hsvDist := compare_utils.CompareHistograms(t1.HistogramHSV, t2.HistogramHSV)
-		colorDist := compare_utils.EuclideanDistance(t1.MeanColor, t2.MeanColor)
-		textureDist := math.Abs(t1.TextureSignature - t2.TextureSignature)
-		phashDist := compare_utils.HammingDistance(t1.PHash, t2.PHash)
-		shapeDist := math.Abs(t1.ShapeSignature - t2.ShapeSignature)
-
-		// Normalisation
-		normRGB := rgbDist / float64(config.Bins*3*255)
-		normHSV := hsvDist / float64(config.Bins*3*255)
-		normColor := colorDist / (255 * math.Sqrt(3))
-		normTexture := textureDist / 1000.0
-		normPHash := float64(phashDist) / 64.0
-		normShape := shapeDist / 1.0
-
-		tileScore := 1 - normRGB*0.1 - normHSV*0.1 - normColor*0.15 - normTexture*0.15 - normShape*0.25 - (1-normPHash)*0.25
-
-		// Correction : tuile très similaire = score parfait
-		if tileScore < 0.85 {
-			tileScoreSum += tileScore
-		} else {
-			tileScoreSum += 1.0
-		}
+	colorDist := compare_utils.EuclideanDistance(desc1.GlobalMeanColor, desc2.GlobalMeanColor)       // Distance euclidienne des moyennes de couleurs
+	textureDist := math.Abs(desc1.GlobalTexture - desc2.GlobalTexture)                               // Différence absolue de texture
+	phashDist := compare_utils.HammingDistance(uint64(desc1.GlobalPHash), uint64(desc2.GlobalPHash)) // Distance de Hamming entre les pHash
+	shapeDist := math.Abs(desc1.GlobalShape - desc2.GlobalShape)                                     // Différence absolue de forme
+
+	global := FeatureDistances{
+		RGB:     histDistance(desc1.GlobalRGB, desc2.GlobalRGB, cfg.HistogramMetric), // Distance des histogrammes RGB
+		HSV:     histDistance(desc1.GlobalHSV, desc2.GlobalHSV, cfg.HistogramMetric), // Distance des histogrammes HSV
+		Color:   colorDist / (255 * math.Sqrt(3)),
+		Texture: textureDist / cfg.GlobalTextureScale,
+		Shape:   shapeDist / 1.0, // car ratio ∈ [0,1]
+		PHash:   float64(phashDist) / 64.0,
+		Alpha:   math.Abs(desc1.GlobalAlphaCoverage - desc2.GlobalAlphaCoverage), // Couverture ∈ [0,1]
 	}
+	globalScore := levelScore(cfg.Global, global)
 
-	// Moyenne des scores des tuiles
-	avgTileScore := tileScoreSum / float64(len(desc1.Tiles))
+	// --- Comparaison tuile par tuile (appariement selon cfg.TileMatching) ---
+	var tileScores []float64
+	var tileMatches []int
+	if b != nil {
+		tileScores = make([]float64, len(desc1.Tiles))
+		tileMatches = make([]int, len(desc1.Tiles))
+	}
+	avgTileScore := gridScore(desc1.Tiles, desc2.Tiles, desc1.Params.TilesPerRow, cfg, tileScores, tileMatches)
+
+	// --- Niveaux de la pyramide spatiale ---
+	// Mêmes paramètres d'analyse, donc mêmes niveaux dans les deux descripteurs ;
+	// un niveau sans part dans cfg.LevelShares n'entre pas dans le score
+	var levelSum float64
+	totalShare := cfg.GlobalShare + cfg.TileShare
+	var levels []LevelBreakdown
+	for i, level := range desc1.Levels {
+		if i >= len(desc2.Levels) || desc2.Levels[i].Grid != level.Grid {
+			continue // Descripteurs non vérifiés par l'appelant : niveau absent de desc2, ignoré
+		}
+		share := cfg.LevelShares[level.Grid]
+		if share == 0 && b == nil {
+			continue // Inutile de comparer un niveau sans poids
+		}
+		var scores []float64
+		var matches []int
+		if b != nil {
+			scores = make([]float64, len(level.Tiles))
+			matches = make([]int, len(level.Tiles))
+		}
+		score := gridScore(level.Tiles, desc2.Levels[i].Tiles, level.Grid, cfg, scores, matches)
+		levelSum += score * share
+		totalShare += share
+		if b != nil {
+			levels = append(levels, LevelBreakdown{
+				Grid:    level.Grid,
+				Score:   score,
+				Tiles:   tileGrid(scores, level.Grid),
+				Matches: matchesDetail(matches, cfg),
+			})
+		}
+	}
 
 	// --- Score final ---
-	finalScore := (globalScore*0.65 + avgTileScore*0.35) * 100
+	// Parts relatives, ramenées à une somme de 1 : deux images identiques
+	// obtiennent 100 quelles que soient les parts choisies
+	var finalScore float64
+	if totalShare > 0 {
+		finalScore = (globalScore*cfg.GlobalShare + avgTileScore*cfg.TileShare + levelSum) / totalShare * 100
+	}
 	if finalScore < 0 {
 		finalScore = 0
 	}
+
+	if b != nil {
+		*b = Breakdown{
+			Global:      global,
+			GlobalScore: globalScore,
+			TileScore:   avgTileScore,
+			Tiles:       tileGrid(tileScores, desc1.Params.TilesPerRow),
+			TileMatches: matchesDetail(tileMatches, cfg),
+			Levels:      levels,
+			Score:       finalScore,
+		}
+	}
 	return finalScore
 }
+
+// tileScore : score d'une paire de tuiles, distances normalisées, après le seuil de similarité
+func tileScore(t1, t2 *model.TileDescriptor, cfg ScoringConfig) float64 {
+	score := levelScore(cfg.Tile, FeatureDistances{
+		RGB:     histDistance(t1.HistogramRGB, t2.HistogramRGB, cfg.HistogramMetric),
+		HSV:     histDistance(t1.HistogramHSV, t2.HistogramHSV, cfg.HistogramMetric),
+		Color:   compare_utils.EuclideanDistance(t1.MeanColor, t2.MeanColor) / (255 * math.Sqrt(3)),
+		Texture: math.Abs(t1.TextureSignature-t2.TextureSignature) / cfg.TileTextureScale,
+		Shape:   math.Abs(t1.ShapeSignature-t2.ShapeSignature) / 1.0,
+		PHash:   float64(compare_utils.HammingDistance(uint64(t1.PHash), uint64(t2.PHash))) / 64.0,
+		Alpha:   math.Abs(t1.AlphaCoverage - t2.AlphaCoverage),
+	})
+
+	// Correction : tuile très similaire = score parfait
+	if score >= cfg.TileSnapThreshold {
+		score = 1.0
+	}
+	return score
+}
+
+/*
+===== DISTANCE ENTRE HISTOGRAMMES =====
+
+raw-l1 (défaut) : somme des écarts de compteurs bruts ramenée par
+Bins×3×255, calcul historique (peut dépasser 1, dépend du nombre de pixels).
+Autres métriques : histogrammes normalisés, distance dans [0, 1].
+*/
+func histDistance(h1, h2 map[string][]int, metric compare_utils.HistogramMetric) float64 {
+	if metric == "" || metric == compare_utils.MetricRawL1 {
+		return compare_utils.CompareHistograms(h1, h2) / float64(config.Bins*3*255)
+	}
+	d, err := compare_utils.CompareHistogramsWith(h1, h2, metric)
+	if err != nil {
+		// Métrique inconnue (impossible après ScoringConfig.usable) : calcul historique
+		return compare_utils.CompareHistograms(h1, h2) / float64(config.Bins*3*255)
+	}
+	return d
+}
+
+// FeatureDistances : distances normalisées (0-1) entre deux images ou deux tuiles
+type FeatureDistances struct {
+	RGB     float64 `json:"rgb"`
+	HSV     float64 `json:"hsv"`
+	Color   float64 `json:"color"`
+	Texture float64 `json:"texture"`
+	Shape   float64 `json:"shape"`
+	PHash   float64 `json:"phash"`
+	Alpha   float64 `json:"alpha"`
+}
+
+/*
+===== DÉTAIL DU SCORE =====
+
+Scores intermédiaires dans [0, 1] (ou négatifs si les poids dépassent 1),
+score final en pourcentage comme CompareDescriptors.
+Tiles[ligne][colonne] : score de chaque tuile après le seuil de similarité
+(les tuiles au-dessus du seuil valent 1).
+TileMatches : hors mode aligned, indice de la tuile appariée à chaque tuile
+(ligne par ligne), -1 si elle n'a pas de correspondante (voir TileMatching).
+Levels : même détail pour chaque niveau de la pyramide spatiale.
+*/
+type Breakdown struct {
+	Global      FeatureDistances `json:"global"`
+	GlobalScore float64          `json:"global_score"`
+	TileScore   float64          `json:"tile_score"`
+	Tiles       [][]float64      `json:"tiles"`
+	TileMatches []int            `json:"tile_matches,omitempty"`
+	Levels      []LevelBreakdown `json:"levels,omitempty"`
+	Score       float64          `json:"score"`
+}
+
+// LevelBreakdown : score moyen, grille des scores et appariement d'un niveau de la pyramide
+type LevelBreakdown struct {
+	Grid    int         `json:"grid"`
+	Score   float64     `json:"score"`
+	Tiles   [][]float64 `json:"tiles"`
+	Matches []int       `json:"matches,omitempty"`
+}
+
+// matchesDetail : appariement à publier dans le détail, sans intérêt en mode aligned
+func matchesDetail(matches []int, cfg ScoringConfig) []int {
+	if cfg.TileMatching == "" || cfg.TileMatching == MatchAligned {
+		return nil
+	}
+	return matches
+}
+
+// tileGrid range les scores des tuiles en lignes de perRow (ordre de l'analyse : ligne par ligne)
+func tileGrid(scores []float64, perRow int) [][]float64 {
+	if perRow <= 0 || len(scores)%perRow != 0 {
+		perRow = max(len(scores), 1) // Grille inconnue : une seule ligne
+	}
+	var grid [][]float64
+	for start := 0; start < len(scores); start += perRow {
+		grid = append(grid, scores[start:start+perRow])
+	}
+	return grid
+}
+
+/*
+===== SCORE D'UN NIVEAU =====
+
+Combine les distances d'un niveau (image ou tuile) selon les poids :
+chaque caractéristique retranche sa distance pondérée, y compris le pHash
+(pHash identiques → rien à retrancher, opposés → tout le poids).
+Deux niveaux identiques obtiennent donc exactement 1.
+*/
+func levelScore(w Weights, d FeatureDistances) float64 {
+	return 1 -
+		d.RGB*w.RGB -
+		d.HSV*w.HSV -
+		d.Color*w.Color -
+		d.Texture*w.Texture -
+		d.Shape*w.Shape -
+		d.PHash*w.PHash -
+		d.Alpha*w.Alpha
+}
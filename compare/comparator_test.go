@@ -0,0 +1,167 @@
+package compare
+
+import (
+	"math"
+	"testing"
+
+	"github.com/MrIsmail1/Golang_images_matcher/compare-utils"
+	"github.com/MrIsmail1/Golang_images_matcher/internal/testbank"
+	"github.com/MrIsmail1/Golang_images_matcher/model"
+)
+
+/*
+===== UNE IMAGE COMPARÉE À ELLE-MÊME =====
+
+Quels que soient les réglages du score, deux descripteurs identiques
+obtiennent exactement 100 (garde-fou du terme pHash et des parts).
+*/
+func TestSelfComparisonIs100(t *testing.T) {
+	params := model.CurrentParams()
+	params.Pyramid = "2,4"
+	descs := testbank.Descriptors(t, params, "chien13.png", "cala1.jpg", "img.png")
+
+	hellinger := DefaultScoring()
+	hellinger.HistogramMetric = compare_utils.MetricHellinger
+	shift := DefaultScoring()
+	shift.TileMatching = MatchShift
+	greedy := DefaultScoring()
+	greedy.TileMatching = MatchGreedy
+	pyramid := DefaultScoring()
+	pyramid.LevelShares = map[int]float64{2: 0.1, 4: 0.2}
+
+	configs := map[string]ScoringConfig{
+		"défaut":    DefaultScoring(),
+		"hellinger": hellinger,
+		"shift":     shift,
+		"greedy":    greedy,
+		"pyramide":  pyramid,
+	}
+	for name, cfg := range configs {
+		for _, desc := range descs {
+			if score := CompareDescriptorsWith(desc, desc, cfg); score != 100 {
+				t.Errorf("%s, %s : %v, 100 attendu", name, desc.ImageName, score)
+			}
+			if b := ExplainDescriptors(desc, desc, cfg); b.Score != 100 {
+				t.Errorf("%s, %s : détail %v, 100 attendu", name, desc.ImageName, b.Score)
+			}
+		}
+	}
+	if score := CompareDescriptors(descs[0], descs[0]); score != 100 {
+		t.Errorf("CompareDescriptors : %v, 100 attendu", score)
+	}
+}
+
+// Deux images différentes restent sous 100
+func TestDifferentImagesBelow100(t *testing.T) {
+	descs := testbank.Descriptors(t, model.CurrentParams(), "chien13.png", "cala1.jpg")
+	if score := CompareDescriptors(descs[0], descs[1]); score >= 100 || score < 0 {
+		t.Errorf("score %v hors de [0, 100[", score)
+	}
+}
+
+// Niveaux de pyramide absents du second descripteur (non vérifié) : ignorés, sans panique
+func TestMissingLevels(t *testing.T) {
+	params := model.CurrentParams()
+	params.Pyramid = "2,4"
+	descs := testbank.Descriptors(t, params, "chien13.png", "chien12.png")
+
+	flat1, flat2 := *descs[0], *descs[1]
+	flat1.Levels, flat2.Levels = nil, nil
+	cfg := DefaultScoring()
+	cfg.LevelShares = map[int]float64{2: 0.1, 4: 0.2}
+
+	want := CompareDescriptorsWith(&flat1, &flat2, cfg)
+	if got := CompareDescriptorsWith(descs[0], &flat2, cfg); got != want {
+		t.Errorf("niveaux absents : %v, %v attendu (niveaux ignorés)", got, want)
+	}
+	if b := ExplainDescriptors(descs[0], &flat2, cfg); len(b.Levels) != 0 || b.Score != want {
+		t.Errorf("détail : %d niveaux, score %v, aucun niveau et %v attendus", len(b.Levels), b.Score, want)
+	}
+}
+
+// Grilles vides ou incohérentes : score de tuiles nul dans tous les modes, sans panique ni NaN
+func TestEmptyTileGrid(t *testing.T) {
+	desc := testbank.Descriptors(t, model.CurrentParams(), "chien13.png")[0]
+	for _, mode := range TileMatchings {
+		cfg := DefaultScoring()
+		cfg.TileMatching = mode
+		for _, grid := range []int{0, -1, desc.Params.TilesPerRow} {
+			if got := gridScore(nil, nil, grid, cfg, nil, nil); got != 0 {
+				t.Errorf("%s, grille %d sans tuiles : %v, 0 attendu", mode, grid, got)
+			}
+		}
+		if got := gridScore(desc.Tiles, desc.Tiles, 0, cfg, nil, nil); got != 0 {
+			t.Errorf("%s, grille 0 : %v, 0 attendu", mode, got)
+		}
+		if got := gridScore(desc.Tiles, desc.Tiles[:1], desc.Params.TilesPerRow, cfg, nil, nil); got != 0 {
+			t.Errorf("%s, tuiles manquantes : %v, 0 attendu", mode, got)
+		}
+
+		noTiles := *desc
+		noTiles.Tiles = nil
+		if score := CompareDescriptorsWith(&noTiles, &noTiles, cfg); math.IsNaN(score) || score < 0 || score > 100 {
+			t.Errorf("%s, descripteur sans tuiles : score %v hors de [0, 100]", mode, score)
+		}
+		if b := ExplainDescriptors(&noTiles, &noTiles, cfg); b.TileScore != 0 {
+			t.Errorf("%s, descripteur sans tuiles : score de tuiles %v, 0 attendu", mode, b.TileScore)
+		}
+	}
+}
+
+// Réglages non validés : jamais de panique ni de NaN, score dans [0, 100]
+func TestUnvalidatedScoringConfig(t *testing.T) {
+	descs := testbank.Descriptors(t, model.CurrentParams(), "chien13.png", "chien12.png")
+
+	unknown := DefaultScoring()
+	unknown.HistogramMetric = "inconnue"
+	unknown.TileMatching = "inconnu"
+	noScale := DefaultScoring()
+	noScale.GlobalTextureScale, noScale.TileTextureScale = 0, 0
+
+	configs := map[string]ScoringConfig{
+		"zéro":            {},
+		"inconnus":        unknown,
+		"échelles nulles": noScale,
+		"niveau invalide": {LevelShares: map[int]float64{1: 0.5}},
+	}
+	for name, cfg := range configs {
+		score := CompareDescriptorsWith(descs[0], descs[1], cfg)
+		if math.IsNaN(score) || score < 0 || score > 100 {
+			t.Errorf("%s : score %v hors de [0, 100]", name, score)
+		}
+		if self := CompareDescriptorsWith(descs[0], descs[0], cfg); self != 100 {
+			t.Errorf("%s : %v sur soi-même, 100 attendu", name, self)
+		}
+	}
+	if got, want := CompareDescriptorsWith(descs[0], descs[1], ScoringConfig{}), CompareDescriptors(descs[0], descs[1]); got != want {
+		t.Errorf("ScoringConfig{} : %v, %v attendu (DefaultScoring)", got, want)
+	}
+	// Seul le reste des réglages invalides reprend sa valeur par défaut
+	if got, want := CompareDescriptorsWith(descs[0], descs[1], configs["niveau invalide"]), CompareDescriptors(descs[0], descs[1]); got != want {
+		t.Errorf("niveau invalide : %v, %v attendu (DefaultScoring)", got, want)
+	}
+}
+
+// Une part de niveau invalide (grille < 2) est ignorée, les parts valides sont gardées
+func TestInvalidLevelShareDropped(t *testing.T) {
+	params := model.CurrentParams()
+	params.Pyramid = "2,4"
+	descs := testbank.Descriptors(t, params, "chien13.png", "chien12.png")
+
+	valid := DefaultScoring()
+	valid.LevelShares = map[int]float64{2: 0.2}
+	mixed := DefaultScoring()
+	mixed.LevelShares = map[int]float64{1: 0.5, 2: 0.2}
+
+	want := CompareDescriptorsWith(descs[0], descs[1], valid)
+	if got := CompareDescriptorsWith(descs[0], descs[1], mixed); got != want {
+		t.Errorf("parts {1: 0.5, 2: 0.2} : %v, %v attendu (part du niveau 1 ignorée)", got, want)
+	}
+	if flat := CompareDescriptors(descs[0], descs[1]); want == flat {
+		t.Errorf("part du niveau 2 sans effet sur le score (%v)", want)
+	}
+	b := ExplainDescriptors(descs[0], descs[1], mixed)
+	if len(b.Levels) != 2 || b.Levels[0].Grid != 2 || b.Levels[1].Grid != 4 {
+		t.Errorf("niveaux détaillés %+v, grilles 2 et 4 attendues", b.Levels)
+	}
+}
@@ -0,0 +1,202 @@
+package compare
+
+import (
+	"sort"
+
+	"github.com/MrIsmail1/Golang_images_matcher/model"
+)
+
+/*
+===== APPARIEMENT DES TUILES =====
+
+À QUOI ÇA SERT :
+Choisit quelle tuile de la seconde image est comparée à chaque tuile de la
+première. En mode aligned, la tuile i n'est comparée qu'à la tuile i : une
+photo décalée d'une largeur de tuile, ou recadrée, perd l'essentiel de son
+score de tuiles alors que son contenu est le même.
+
+MODES :
+- aligned : tuiles de même position (historique, le plus rapide)
+- shift   : meilleur décalage de toute la grille, dans un voisinage de TileShiftRadius tuiles
+- greedy  : chaque tuile appariée à la meilleure tuile libre, où qu'elle soit
+
+COÛT (grille 9×9) : 81 comparaisons de tuiles en aligned, jusqu'à
+(2×rayon+1)² × 81 en shift, 81² en greedy. Le mode greedy ignore la
+disposition : deux images aux mêmes tuiles mélangées obtiennent 100 %.
+*/
+type TileMatching string
+
+const (
+	MatchAligned TileMatching = "aligned"
+	MatchShift   TileMatching = "shift"
+	MatchGreedy  TileMatching = "greedy"
+)
+
+// TileMatchings liste les modes d'appariement disponibles
+var TileMatchings = []TileMatching{MatchAligned, MatchShift, MatchGreedy}
+
+// Valid indique si le mode est connu
+func (m TileMatching) Valid() bool {
+	for _, known := range TileMatchings {
+		if m == known {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+===== SCORE D'UNE GRILLE DE TUILES =====
+
+Apparie les tuiles des deux grilles grid×grid selon cfg.TileMatching et
+retourne la moyenne des scores des paires retenues, après le seuil de
+similarité. Si scores et matches ne sont pas nil, ils reçoivent pour
+chaque tuile de tiles1 son score et l'indice de la tuile appariée dans
+tiles2 (-1 et score 0 si elle n'a pas de correspondante).
+*/
+func gridScore(tiles1, tiles2 []model.TileDescriptor, grid int, cfg ScoringConfig, scores []float64, matches []int) float64 {
+	// Grille vide ou de taille incohérente (descripteurs non vérifiés) : aucune tuile comparable
+	if grid <= 0 || len(tiles1) == 0 || len(tiles1) != grid*grid || len(tiles2) != len(tiles1) {
+		for i := range scores {
+			scores[i], matches[i] = 0, -1
+		}
+		return 0
+	}
+
+	switch cfg.TileMatching {
+	case MatchShift:
+		return shiftScore(tiles1, tiles2, grid, cfg, scores, matches)
+	case MatchGreedy:
+		return greedyScore(tiles1, tiles2, grid, cfg, scores, matches)
+	}
+
+	var tileScoreSum float64
+	for i := range tiles1 {
+		score := tileScore(&tiles1[i], &tiles2[i], cfg)
+		tileScoreSum += score
+		if scores != nil {
+			scores[i], matches[i] = score, i
+		}
+	}
+
+	// Moyenne des scores des tuiles
+	return tileScoreSum / float64(len(tiles1))
+}
+
+/*
+===== MEILLEUR DÉCALAGE DE LA GRILLE =====
+
+Essaie chaque décalage (dx, dy) de -r à r tuiles : la tuile (x, y) est
+comparée à la tuile (x+dx, y+dy), la moyenne porte sur les tuiles qui ont
+une correspondante. Le rayon r est borné à (grid-1)/2 : les tuiles
+comparées couvrent au moins la moitié de la grille sur chaque axe.
+Les décalages sont essayés du plus court au plus long et seul un score
+strictement meilleur l'emporte : à égalité, la grille alignée gagne.
+*/
+func shiftScore(tiles1, tiles2 []model.TileDescriptor, grid int, cfg ScoringConfig, scores []float64, matches []int) float64 {
+	if grid <= 0 || len(tiles1) == 0 {
+		return 0 // Aucun décalage à essayer : best resterait à -1
+	}
+	r := min(cfg.TileShiftRadius, (grid-1)/2)
+
+	type offset struct{ dx, dy int }
+	var offsets []offset
+	for dy := -r; dy <= r; dy++ {
+		for dx := -r; dx <= r; dx++ {
+			offsets = append(offsets, offset{dx, dy})
+		}
+	}
+	sort.SliceStable(offsets, func(a, b int) bool {
+		return max(abs(offsets[a].dx), abs(offsets[a].dy)) < max(abs(offsets[b].dx), abs(offsets[b].dy))
+	})
+
+	best, bestOffset := -1.0, offset{}
+	for _, o := range offsets {
+		var sum float64
+		var n int
+		for y := max(0, -o.dy); y < min(grid, grid-o.dy); y++ {
+			for x := max(0, -o.dx); x < min(grid, grid-o.dx); x++ {
+				sum += tileScore(&tiles1[y*grid+x], &tiles2[(y+o.dy)*grid+x+o.dx], cfg)
+				n++
+			}
+		}
+		if avg := sum / float64(n); avg > best {
+			best, bestOffset = avg, o
+		}
+	}
+
+	// Détail des tuiles pour le décalage retenu
+	if scores != nil {
+		for i := range tiles1 {
+			x, y := i%grid+bestOffset.dx, i/grid+bestOffset.dy
+			if x < 0 || x >= grid || y < 0 || y >= grid {
+				scores[i], matches[i] = 0, -1
+				continue
+			}
+			scores[i], matches[i] = tileScore(&tiles1[i], &tiles2[y*grid+x], cfg), y*grid+x
+		}
+	}
+	return best
+}
+
+/*
+===== APPARIEMENT GLOUTON =====
+
+Calcule le score de toutes les paires de tuiles, puis les retient de la
+meilleure à la moins bonne tant que leurs deux tuiles sont libres.
+À score égal, la paire la moins déplacée passe en premier : deux images
+identiques restent appariées tuile à tuile. Toutes les tuiles trouvent
+une correspondante (grilles de même taille).
+*/
+func greedyScore(tiles1, tiles2 []model.TileDescriptor, grid int, cfg ScoringConfig, scores []float64, matches []int) float64 {
+	if grid <= 0 || len(tiles1) == 0 {
+		return 0 // Pas de paire : la moyenne diviserait par zéro
+	}
+	type pair struct {
+		i, j  int
+		score float64
+		moved int
+	}
+	pairs := make([]pair, 0, len(tiles1)*len(tiles2))
+	for i := range tiles1 {
+		for j := range tiles2 {
+			moved := abs(i%grid-j%grid) + abs(i/grid-j/grid)
+			pairs = append(pairs, pair{i, j, tileScore(&tiles1[i], &tiles2[j], cfg), moved})
+		}
+	}
+	sort.Slice(pairs, func(a, b int) bool {
+		pa, pb := pairs[a], pairs[b]
+		if pa.score != pb.score {
+			return pa.score > pb.score
+		}
+		if pa.moved != pb.moved {
+			return pa.moved < pb.moved
+		}
+		if pa.i != pb.i {
+			return pa.i < pb.i
+		}
+		return pa.j < pb.j
+	})
+
+	used1 := make([]bool, len(tiles1))
+	used2 := make([]bool, len(tiles2))
+	var sum float64
+	for _, p := range pairs {
+		if used1[p.i] || used2[p.j] {
+			continue
+		}
+		used1[p.i], used2[p.j] = true, true
+		sum += p.score
+		if scores != nil {
+			scores[p.i], matches[p.i] = p.score, p.j
+		}
+	}
+	return sum / float64(len(tiles1))
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,253 @@
+package compare
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+
+	"github.com/MrIsmail1/Golang_images_matcher/compare-utils"
+)
+
+/*
+===== POIDS DES CARACTÉRISTIQUES =====
+
+Part de chaque distance normalisée (0-1) retranchée au score d'un niveau
+(image entière ou tuile). Avec des poids de somme 1, le score reste dans [0, 1].
+*/
+type Weights struct {
+	RGB     float64 `json:"rgb"`
+	HSV     float64 `json:"hsv"`
+	Color   float64 `json:"color"`
+	Texture float64 `json:"texture"`
+	Shape   float64 `json:"shape"`
+	PHash   float64 `json:"phash"`
+	Alpha   float64 `json:"alpha"` // Écart de couverture alpha (0 par défaut : photos opaques)
+}
+
+/*
+===== CONFIGURATION DU SCORE =====
+
+À QUOI ÇA SERT :
+Regroupe tous les réglages de CompareDescriptorsWith, pour que chaque équipe
+ajuste le score à son propre corpus sans modifier le code.
+DefaultScoring garde les poids historiques de CompareDescriptors, avec le
+terme pHash corrigé (une image comparée à elle-même obtient 100, et non 75).
+
+FICHIER DE CONFIGURATION (JSON) :
+Les champs absents gardent leur valeur par défaut, les champs inconnus sont
+refusés (faute de frappe). Exemple :
+
+	{
+	  "global": {"rgb": 0.2, "hsv": 0.1, "color": 0.1, "texture": 0.1, "shape": 0.25, "phash": 0.25},
+	  "tile_snap_threshold": 0.9,
+	  "histogram_metric": "hellinger",
+	  "global_share": 0.5, "tile_share": 0.3, "level_shares": {"2": 0.1, "4": 0.1}
+	}
+*/
+type ScoringConfig struct {
+	// Global, Tile : poids des caractéristiques pour l'image entière et pour chaque tuile
+	Global Weights `json:"global"`
+	Tile   Weights `json:"tile"`
+
+	// GlobalTextureScale, TileTextureScale : écart de texture ramené à 1 (normalisation)
+	GlobalTextureScale float64 `json:"global_texture_scale"`
+	TileTextureScale   float64 `json:"tile_texture_scale"`
+
+	// TileSnapThreshold : score de tuile à partir duquel la tuile compte comme identique (1)
+	TileSnapThreshold float64 `json:"tile_snap_threshold"`
+
+	// TileMatching : appariement des tuiles des deux images (voir TileMatching), aligned par défaut.
+	// TileShiftRadius : décalage maximal en tuiles essayé en mode shift (1 par défaut)
+	TileMatching    TileMatching `json:"tile_matching"`
+	TileShiftRadius int          `json:"tile_shift_radius"`
+
+	// GlobalShare, TileShare : part du score global et de la moyenne des tuiles dans le score final
+	GlobalShare float64 `json:"global_share"`
+	TileShare   float64 `json:"tile_share"`
+
+	// LevelShares : part du score moyen de chaque niveau de la pyramide spatiale,
+	// indexée par sa grille (ex. {"2": 0.1, "4": 0.1}). Niveau absent : part nulle.
+	// Les parts (global, tuiles et niveaux présents) sont relatives : le score final
+	// est divisé par leur somme et reste sur 100.
+	LevelShares map[int]float64 `json:"level_shares"`
+
+	// HistogramMetric : distance entre histogrammes (voir compare_utils.HistogramMetric),
+	// raw-l1 par défaut (compteurs bruts, calcul historique)
+	HistogramMetric compare_utils.HistogramMetric `json:"histogram_metric"`
+}
+
+// DefaultScoring retourne les poids historiques de CompareDescriptors (terme pHash corrigé)
+func DefaultScoring() ScoringConfig {
+	w := Weights{RGB: 0.1, HSV: 0.1, Color: 0.15, Texture: 0.15, Shape: 0.25, PHash: 0.25}
+	return ScoringConfig{
+		Global:             w,
+		Tile:               w,
+		GlobalTextureScale: 500,
+		TileTextureScale:   1000,
+		TileSnapThreshold:  0.85,
+		TileMatching:       MatchAligned,
+		TileShiftRadius:    1,
+		GlobalShare:        0.65,
+		TileShare:          0.35,
+		HistogramMetric:    compare_utils.MetricRawL1,
+	}
+}
+
+/*
+===== VALIDATION =====
+
+Refuse les réglages qui rendraient le score absurde : poids ou parts
+négatifs, poids d'un niveau tous nuls ou seuil de similarité des tuiles
+nul (toute image à 100), niveau de pyramide de grille inférieure à 2,
+échelles de texture nulles (division par zéro), métrique d'histogramme
+ou appariement des tuiles inconnus, rayon de décalage négatif.
+*/
+func (c ScoringConfig) Validate() error {
+	for name, w := range map[string]Weights{"global": c.Global, "tile": c.Tile} {
+		for field, v := range map[string]float64{
+			"rgb": w.RGB, "hsv": w.HSV, "color": w.Color,
+			"texture": w.Texture, "shape": w.Shape, "phash": w.PHash, "alpha": w.Alpha,
+		} {
+			if v < 0 {
+				return fmt.Errorf("scoring: poids %s.%s négatif (%g)", name, field, v)
+			}
+		}
+		if w == (Weights{}) {
+			return fmt.Errorf("scoring: poids %s tous nuls (score toujours de 100)", name)
+		}
+	}
+	if c.GlobalTextureScale <= 0 || c.TileTextureScale <= 0 {
+		return fmt.Errorf("scoring: échelles de texture strictement positives attendues")
+	}
+	if !(c.TileSnapThreshold > 0) {
+		return fmt.Errorf("scoring: seuil de similarité des tuiles strictement positif attendu (%g)", c.TileSnapThreshold)
+	}
+	total := c.GlobalShare + c.TileShare
+	for grid, share := range c.LevelShares {
+		if grid < 2 || share < 0 {
+			return fmt.Errorf("scoring: part %g du niveau de pyramide %d invalide (grille ≥ 2, part positive)", share, grid)
+		}
+		total += share
+	}
+	if c.GlobalShare < 0 || c.TileShare < 0 || total == 0 {
+		return fmt.Errorf("scoring: parts global/tuiles/pyramide positives et non toutes nulles attendues")
+	}
+	if c.TileMatching != "" && !c.TileMatching.Valid() {
+		return fmt.Errorf("scoring: appariement des tuiles %q inconnu (disponibles : %v)", c.TileMatching, TileMatchings)
+	}
+	if c.TileShiftRadius < 0 {
+		return fmt.Errorf("scoring: rayon de décalage des tuiles négatif (%d)", c.TileShiftRadius)
+	}
+	if c.HistogramMetric != "" && !c.HistogramMetric.Valid() {
+		return fmt.Errorf("scoring: métrique d'histogramme %q inconnue (disponibles : %v)", c.HistogramMetric, compare_utils.HistogramMetrics)
+	}
+	return nil
+}
+
+/*
+===== RÉGLAGES UTILISABLES =====
+
+Version tolérante de Validate pour le calcul du score, qui n'a pas de
+retour d'erreur : la valeur zéro ScoringConfig{} vaut DefaultScoring(),
+et chaque champ que Validate refuserait reprend sa valeur par défaut
+(un niveau de pyramide invalide est ignoré). Jamais de panique ni de
+score NaN pour des réglages non validés.
+*/
+func (c ScoringConfig) usable() ScoringConfig {
+	def := DefaultScoring()
+	if reflect.ValueOf(c).IsZero() {
+		return def
+	}
+
+	if !c.Global.valid() {
+		c.Global = def.Global
+	}
+	if !c.Tile.valid() {
+		c.Tile = def.Tile
+	}
+	if !(c.TileSnapThreshold > 0) {
+		c.TileSnapThreshold = def.TileSnapThreshold
+	}
+	if !(c.GlobalTextureScale > 0) {
+		c.GlobalTextureScale = def.GlobalTextureScale
+	}
+	if !(c.TileTextureScale > 0) {
+		c.TileTextureScale = def.TileTextureScale
+	}
+	if !(c.GlobalShare >= 0 && c.TileShare >= 0) {
+		c.GlobalShare, c.TileShare = def.GlobalShare, def.TileShare
+	}
+	total := c.GlobalShare + c.TileShare
+	for grid, share := range c.LevelShares {
+		if grid < 2 || !(share >= 0) {
+			c.LevelShares = validLevelShares(c.LevelShares)
+			break
+		}
+	}
+	for _, share := range c.LevelShares {
+		total += share
+	}
+	if total == 0 {
+		c.GlobalShare, c.TileShare = def.GlobalShare, def.TileShare
+	}
+	if !c.TileMatching.Valid() {
+		c.TileMatching = def.TileMatching
+	}
+	if c.TileShiftRadius < 0 {
+		c.TileShiftRadius = def.TileShiftRadius
+	}
+	if !c.HistogramMetric.Valid() {
+		c.HistogramMetric = def.HistogramMetric
+	}
+	return c
+}
+
+// valid indique si aucun poids n'est négatif (ni NaN) et s'ils ne sont pas tous nuls
+func (w Weights) valid() bool {
+	if w == (Weights{}) {
+		return false
+	}
+	for _, v := range [...]float64{w.RGB, w.HSV, w.Color, w.Texture, w.Shape, w.PHash, w.Alpha} {
+		if !(v >= 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// validLevelShares copie les parts de pyramide sans les niveaux invalides
+func validLevelShares(shares map[int]float64) map[int]float64 {
+	valid := make(map[int]float64, len(shares))
+	for grid, share := range shares {
+		if grid >= 2 && share >= 0 {
+			valid[grid] = share
+		}
+	}
+	return valid
+}
+
+/*
+===== CHARGEMENT D'UN FICHIER DE CONFIGURATION =====
+
+Part des réglages par défaut et n'écrase que les champs présents dans le fichier.
+*/
+func LoadScoringConfig(path string) (ScoringConfig, error) {
+	cfg := DefaultScoring()
+
+	f, err := os.Open(path)
+	if err != nil {
+		return cfg, err
+	}
+	defer f.Close()
+
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		return cfg, fmt.Errorf("%s : %w", path, err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return cfg, fmt.Errorf("%s : %w", path, err)
+	}
+	return cfg, nil
+}
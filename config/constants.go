@@ -13,4 +13,13 @@ const (
 
 	// Bins : Nombre d'intervalles pour les histogrammes de couleur
 	Bins = 64
+
+	// Normalize : ramenée à la taille standard par défaut (stretch, pad, crop ou fit)
+	Normalize = "stretch"
+
+	// Alpha : traitement des pixels transparents par défaut (black, ignore ou background)
+	Alpha = "black"
+
+	// Tiling : découpage de la grille de tuiles par défaut (truncate ou cover)
+	Tiling = "truncate"
 )
@@ -2,4 +2,9 @@ module github.com/MrIsmail1/Golang_images_matcher
 
 go 1.24.1
 
-require golang.org/x/image v0.26.0
\ No newline at end of file
+require (
+	go.etcd.io/bbolt v1.4.3
+	golang.org/x/image v0.26.0
+)
+
+require golang.org/x/sys v0.29.0 // indirect
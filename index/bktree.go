@@ -0,0 +1,205 @@
+package index
+
+import (
+	"math/bits"
+	"sort"
+)
+
+/*
+===== BK-TREE SUR LES pHash =====
+
+À QUOI ÇA SERT :
+Retrouve les hashes proches d'un hash donné sans parcourir toute la banque.
+Sert de pré-filtre : seules les images dont le pHash global est proche
+passent ensuite par la comparaison complète (coûteuse).
+
+PRINCIPE (Burkhard-Keller) :
+Chaque nœud porte un hash ; ses enfants sont rangés par distance de
+Hamming au nœud (0-64). Par l'inégalité triangulaire, pour une requête
+à distance d du nœud, les hashes à distance ≤ r de la requête sont
+forcément dans les enfants d'étiquette comprise entre d-r et d+r :
+les autres branches sont ignorées.
+
+IDENTIFIANTS :
+Chaque hash est associé à un identifiant entier choisi par l'appelant
+(ex: position de l'image dans la banque). Plusieurs identifiants peuvent
+partager le même hash (doublons exacts).
+*/
+type BKTree struct {
+	root *bkNode
+	size int
+}
+
+type bkNode struct {
+	hash     uint64
+	ids      []int
+	children map[int]*bkNode // Enfants indexés par distance au nœud
+}
+
+/*
+===== RÉSULTAT D'UNE RECHERCHE =====
+
+Un identifiant et la distance de Hamming entre son hash et la requête.
+*/
+type Neighbor struct {
+	ID       int
+	Distance int
+}
+
+// NewBKTree crée un arbre vide
+func NewBKTree() *BKTree {
+	return &BKTree{}
+}
+
+// hamming compte les bits différents entre deux hashes
+func hamming(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// Len retourne le nombre d'identifiants indexés
+func (t *BKTree) Len() int {
+	return t.size
+}
+
+// Add indexe l'identifiant id sous le hash h
+func (t *BKTree) Add(h uint64, id int) {
+	t.size++
+	if t.root == nil {
+		t.root = &bkNode{hash: h, ids: []int{id}}
+		return
+	}
+
+	n := t.root
+	for {
+		d := hamming(h, n.hash)
+		if d == 0 {
+			n.ids = append(n.ids, id)
+			return
+		}
+		child, ok := n.children[d]
+		if !ok {
+			if n.children == nil {
+				n.children = make(map[int]*bkNode)
+			}
+			n.children[d] = &bkNode{hash: h, ids: []int{id}}
+			return
+		}
+		n = child
+	}
+}
+
+/*
+===== RECHERCHE PAR RAYON =====
+
+Retourne tous les identifiants dont le hash est à distance de Hamming
+≤ radius de h, triés par distance croissante puis par identifiant.
+*/
+func (t *BKTree) Within(h uint64, radius int) []Neighbor {
+	var out []Neighbor
+	if t.root == nil || radius < 0 {
+		return out
+	}
+
+	stack := []*bkNode{t.root}
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		d := hamming(h, n.hash)
+		if d <= radius {
+			for _, id := range n.ids {
+				out = append(out, Neighbor{ID: id, Distance: d})
+			}
+		}
+		for label, child := range n.children {
+			if label >= d-radius && label <= d+radius {
+				stack = append(stack, child)
+			}
+		}
+	}
+
+	sortNeighbors(out)
+	return out
+}
+
+/*
+===== K PLUS PROCHES VOISINS =====
+
+Retourne les k identifiants les plus proches de h (distance de Hamming),
+triés par distance croissante puis par identifiant.
+maxDistance < 0 = pas de limite de distance.
+
+PRINCIPE :
+Le rayon de recherche se resserre au fil du parcours : une fois k voisins
+trouvés, seules les branches pouvant contenir mieux que le k-ième restent
+explorées. Pour départager les ex-aequo de façon stable, tous les
+identifiants à la distance du k-ième sont gardés puis tronqués à la fin.
+*/
+func (t *BKTree) Nearest(h uint64, k, maxDistance int) []Neighbor {
+	if t.root == nil || k <= 0 {
+		return nil
+	}
+	if maxDistance < 0 || maxDistance > 64 {
+		maxDistance = 64
+	}
+
+	// Nombre d'identifiants trouvés à chaque distance
+	var counts [65]int
+	var found []Neighbor
+	radius := maxDistance
+
+	// tighten réduit le rayon à la plus petite distance qui contient déjà k voisins
+	tighten := func() {
+		total := 0
+		for d := 0; d <= radius; d++ {
+			total += counts[d]
+			if total >= k {
+				radius = d
+				return
+			}
+		}
+	}
+
+	stack := []*bkNode{t.root}
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		d := hamming(h, n.hash)
+		if d <= radius {
+			for _, id := range n.ids {
+				found = append(found, Neighbor{ID: id, Distance: d})
+			}
+			counts[d] += len(n.ids)
+			tighten()
+		}
+		for label, child := range n.children {
+			if label >= d-radius && label <= d+radius {
+				stack = append(stack, child)
+			}
+		}
+	}
+
+	// Des voisins trouvés avant le resserrement peuvent dépasser le rayon final
+	out := found[:0]
+	for _, nb := range found {
+		if nb.Distance <= radius {
+			out = append(out, nb)
+		}
+	}
+	sortNeighbors(out)
+	if len(out) > k {
+		out = out[:k]
+	}
+	return out
+}
+
+// sortNeighbors trie par distance croissante puis par identifiant
+func sortNeighbors(ns []Neighbor) {
+	sort.Slice(ns, func(i, j int) bool {
+		if ns[i].Distance != ns[j].Distance {
+			return ns[i].Distance < ns[j].Distance
+		}
+		return ns[i].ID < ns[j].ID
+	})
+}
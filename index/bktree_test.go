@@ -0,0 +1,72 @@
+package index
+
+import (
+	"reflect"
+	"testing"
+)
+
+// testBKTree : hashes à 0, 1, 2, 2 (doublon) et 5 bits de 0, identifiants 0 à 4
+func testBKTree() *BKTree {
+	t := NewBKTree()
+	for id, h := range []uint64{0, 0b1, 0b11, 0b11, 0b11111} {
+		t.Add(h, id)
+	}
+	return t
+}
+
+// Le rayon est inclusif : distance r retenue, r+1 écartée
+func TestBKTreeWithinRadius(t *testing.T) {
+	tree := testBKTree()
+	cases := []struct {
+		radius int
+		want   []Neighbor
+	}{
+		{-1, nil},
+		{0, []Neighbor{{0, 0}}},
+		{1, []Neighbor{{0, 0}, {1, 1}}},
+		{2, []Neighbor{{0, 0}, {1, 1}, {2, 2}, {3, 2}}},
+		{4, []Neighbor{{0, 0}, {1, 1}, {2, 2}, {3, 2}}},
+		{5, []Neighbor{{0, 0}, {1, 1}, {2, 2}, {3, 2}, {4, 5}}},
+	}
+	for _, c := range cases {
+		if got := tree.Within(0, c.radius); len(got) != len(c.want) || (len(got) > 0 && !reflect.DeepEqual(got, c.want)) {
+			t.Errorf("rayon %d : %v, %v attendu", c.radius, got, c.want)
+		}
+	}
+	if got := NewBKTree().Within(0, 64); len(got) != 0 {
+		t.Errorf("arbre vide : %v", got)
+	}
+}
+
+// k au-delà de la taille de l'arbre : tous les identifiants, triés
+func TestBKTreeNearestBeyondSize(t *testing.T) {
+	tree := testBKTree()
+	want := []Neighbor{{0, 0}, {1, 1}, {2, 2}, {3, 2}, {4, 5}}
+	if got := tree.Nearest(0, 10, -1); !reflect.DeepEqual(got, want) {
+		t.Errorf("k = 10 : %v, %v attendu", got, want)
+	}
+	if got := tree.Nearest(0, 10, 2); !reflect.DeepEqual(got, want[:4]) {
+		t.Errorf("k = 10, distance ≤ 2 : %v, %v attendu", got, want[:4])
+	}
+	if got := tree.Nearest(0, 0, -1); got != nil {
+		t.Errorf("k = 0 : %v", got)
+	}
+}
+
+// Doublons exacts : tous comptés et retournés, départagés par identifiant
+func TestBKTreeDuplicates(t *testing.T) {
+	tree := testBKTree()
+	if tree.Len() != 5 {
+		t.Errorf("Len : %d, 5 attendu", tree.Len())
+	}
+	if got, want := tree.Within(0b11, 0), []Neighbor{{2, 0}, {3, 0}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Within doublons : %v, %v attendu", got, want)
+	}
+	if got, want := tree.Nearest(0b11, 1, -1), []Neighbor{{2, 0}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Nearest k = 1 : %v, %v attendu", got, want)
+	}
+	// Ex-aequo au k-ième rang : le plus petit identifiant l'emporte
+	if got, want := tree.Nearest(0, 3, -1), []Neighbor{{0, 0}, {1, 1}, {2, 2}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Nearest k = 3 : %v, %v attendu", got, want)
+	}
+}
@@ -0,0 +1,560 @@
+package index
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+)
+
+/*
+===== INDEX HNSW (PLUS PROCHES VOISINS APPROCHÉS) =====
+
+À QUOI ÇA SERT :
+Retrouve rapidement les vecteurs les plus proches d'un vecteur requête
+(distance euclidienne) dans une grande banque, sans la parcourir entière.
+Le résultat est approché : un voisin peut manquer, d'où son usage comme
+générateur de candidats avant un calcul de score exact.
+
+PRINCIPE (Hierarchical Navigable Small World, Malkov & Yashunin) :
+Les vecteurs forment un graphe de voisinage à plusieurs niveaux. Les
+niveaux hauts, peu peuplés, servent de « voies rapides » ; la recherche
+y descend gloutonnement vers la zone de la requête, puis explore le
+niveau 0 (qui contient tous les vecteurs) en gardant les ef meilleurs.
+
+SUPPRESSION :
+Un vecteur supprimé est marqué et n'apparaît plus dans les résultats,
+mais reste dans le graphe pour ne pas couper les chemins de recherche.
+
+CONCURRENCE :
+Les recherches peuvent être simultanées ; Add et Delete les excluent.
+*/
+type HNSW struct {
+	mu       sync.RWMutex
+	cfg      HNSWConfig
+	dim      int
+	nodes    []hnswNode
+	ids      map[int]int32 // Identifiant → nœud actif
+	entry    int32         // Point d'entrée (nœud du niveau le plus haut), -1 si vide
+	maxLevel int
+	live     int // Nombre de vecteurs non supprimés
+	rng      *rand.Rand
+}
+
+type hnswNode struct {
+	id      int
+	vec     []float32
+	links   [][]int32 // Voisins par niveau (0 à niveau du nœud)
+	deleted bool
+}
+
+/*
+===== RÉGLAGES DE L'INDEX =====
+
+La valeur zéro est utilisable (valeurs par défaut ci-dessous).
+*/
+type HNSWConfig struct {
+	// M : nombre de voisins par nœud et par niveau (2×M au niveau 0), défaut 16, au moins 2
+	M int
+
+	// EfConstruction : largeur de recherche à l'insertion (qualité du graphe), défaut 200
+	EfConstruction int
+
+	// EfSearch : largeur de recherche des requêtes (rappel contre vitesse), défaut 64
+	EfSearch int
+
+	// Seed : graine du tirage des niveaux, pour des index reproductibles
+	Seed int64
+}
+
+// withDefaults complète les réglages non renseignés
+func (c HNSWConfig) withDefaults() HNSWConfig {
+	switch {
+	case c.M <= 0:
+		c.M = 16
+	case c.M == 1:
+		c.M = 2 // Tirage des niveaux en -log(u)/log(M) : infini avec M = 1
+	}
+	if c.EfConstruction <= 0 {
+		c.EfConstruction = 200
+	}
+	if c.EfSearch <= 0 {
+		c.EfSearch = 64
+	}
+	return c
+}
+
+/*
+===== RÉSULTAT D'UNE RECHERCHE VECTORIELLE =====
+
+Un identifiant et la distance euclidienne au carré entre son vecteur
+et la requête.
+*/
+type VectorNeighbor struct {
+	ID       int
+	Distance float32
+}
+
+// ErrHNSW : fichier d'index HNSW illisible ou d'un format inconnu
+var ErrHNSW = errors.New("index HNSW invalide")
+
+// NewHNSW crée un index vide pour des vecteurs de dimension dim
+func NewHNSW(dim int, cfg HNSWConfig) *HNSW {
+	cfg = cfg.withDefaults()
+	return &HNSW{
+		cfg:   cfg,
+		dim:   dim,
+		ids:   make(map[int]int32),
+		entry: -1,
+		rng:   rand.New(rand.NewSource(cfg.Seed)),
+	}
+}
+
+// Dim retourne la dimension des vecteurs de l'index
+func (h *HNSW) Dim() int {
+	return h.dim
+}
+
+// Len retourne le nombre de vecteurs indexés (hors supprimés)
+func (h *HNSW) Len() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.live
+}
+
+// Contains indique si l'identifiant est présent (et non supprimé)
+func (h *HNSW) Contains(id int) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	_, ok := h.ids[id]
+	return ok
+}
+
+// distance : carré de la distance euclidienne
+func distance(a, b []float32) float32 {
+	var sum float32
+	for i := range a {
+		d := a[i] - b[i]
+		sum += d * d
+	}
+	return sum
+}
+
+/*
+===== AJOUT D'UN VECTEUR =====
+
+Un identifiant déjà présent est remplacé (l'ancien vecteur est supprimé).
+Le vecteur est copié : l'appelant peut réutiliser son tableau.
+*/
+func (h *HNSW) Add(id int, vec []float32) error {
+	if len(vec) != h.dim {
+		return fmt.Errorf("index: vecteur de dimension %d, %d attendue", len(vec), h.dim)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.deleteLocked(id)
+
+	// Niveau tiré selon une loi exponentielle : chaque niveau ~M fois moins peuplé
+	level := int(-math.Log(1-h.rng.Float64()) / math.Log(float64(h.cfg.M)))
+	n := int32(len(h.nodes))
+	h.nodes = append(h.nodes, hnswNode{
+		id:    id,
+		vec:   append([]float32(nil), vec...),
+		links: make([][]int32, level+1),
+	})
+	h.ids[id] = n
+	h.live++
+
+	if h.entry < 0 {
+		h.entry, h.maxLevel = n, level
+		return nil
+	}
+
+	// Descente gloutonne par les niveaux au-dessus de celui du nouveau nœud
+	cur := h.entry
+	for l := h.maxLevel; l > level; l-- {
+		cur = h.greedy(vec, cur, l)
+	}
+
+	// Connexion aux plus proches voisins de chaque niveau commun
+	for l := min(level, h.maxLevel); l >= 0; l-- {
+		found := h.searchLayer(vec, cur, h.cfg.EfConstruction, l)
+		neighbors := h.closest(found, h.maxLinks(l))
+		h.nodes[n].links[l] = neighbors
+		for _, nb := range neighbors {
+			h.link(nb, n, l)
+		}
+		cur = found[0].node
+	}
+
+	if level > h.maxLevel {
+		h.entry, h.maxLevel = n, level
+	}
+	return nil
+}
+
+// maxLinks : nombre maximum de voisins d'un nœud au niveau l
+func (h *HNSW) maxLinks(level int) int {
+	if level == 0 {
+		return 2 * h.cfg.M
+	}
+	return h.cfg.M
+}
+
+// link ajoute to aux voisins de from au niveau l, en élaguant au besoin les plus lointains
+func (h *HNSW) link(from, to int32, level int) {
+	links := append(h.nodes[from].links[level], to)
+	if len(links) > h.maxLinks(level) {
+		cands := make([]candidate, len(links))
+		for i, nb := range links {
+			cands[i] = candidate{node: nb, dist: distance(h.nodes[from].vec, h.nodes[nb].vec)}
+		}
+		sortCandidates(cands)
+		links = h.closest(cands, h.maxLinks(level))
+	}
+	h.nodes[from].links[level] = links
+}
+
+// closest retourne les nœuds des n premiers candidats (déjà triés)
+func (h *HNSW) closest(cands []candidate, n int) []int32 {
+	if len(cands) > n {
+		cands = cands[:n]
+	}
+	out := make([]int32, len(cands))
+	for i, c := range cands {
+		out[i] = c.node
+	}
+	return out
+}
+
+/*
+===== SUPPRESSION =====
+
+Retourne false si l'identifiant est absent.
+*/
+func (h *HNSW) Delete(id int) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.deleteLocked(id)
+}
+
+func (h *HNSW) deleteLocked(id int) bool {
+	n, ok := h.ids[id]
+	if !ok {
+		return false
+	}
+	h.nodes[n].deleted = true
+	delete(h.ids, id)
+	h.live--
+	return true
+}
+
+/*
+===== RECHERCHE DES K PLUS PROCHES =====
+
+Retourne au plus k voisins non supprimés, du plus proche au plus lointain
+(à distance égale, par identifiant croissant).
+*/
+func (h *HNSW) Search(vec []float32, k int) ([]VectorNeighbor, error) {
+	if len(vec) != h.dim {
+		return nil, fmt.Errorf("index: vecteur de dimension %d, %d attendue", len(vec), h.dim)
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if h.entry < 0 || k <= 0 {
+		return nil, nil
+	}
+
+	cur := h.entry
+	for l := h.maxLevel; l > 0; l-- {
+		cur = h.greedy(vec, cur, l)
+	}
+
+	// Les nœuds supprimés occupent de la place dans la recherche : on élargit d'autant
+	ef := max(h.cfg.EfSearch, k) + len(h.nodes) - h.live
+	found := h.searchLayer(vec, cur, ef, 0)
+
+	out := make([]VectorNeighbor, 0, k)
+	for _, c := range found {
+		if h.nodes[c.node].deleted {
+			continue
+		}
+		out = append(out, VectorNeighbor{ID: h.nodes[c.node].id, Distance: c.dist})
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Distance != out[j].Distance {
+			return out[i].Distance < out[j].Distance
+		}
+		return out[i].ID < out[j].ID
+	})
+	if len(out) > k {
+		out = out[:k]
+	}
+	return out, nil
+}
+
+// greedy descend vers le voisin le plus proche de vec au niveau l, tant qu'il y en a un meilleur
+func (h *HNSW) greedy(vec []float32, cur int32, level int) int32 {
+	best := distance(vec, h.nodes[cur].vec)
+	for improved := true; improved; {
+		improved = false
+		for _, nb := range h.nodes[cur].links[level] {
+			if d := distance(vec, h.nodes[nb].vec); d < best {
+				best, cur, improved = d, nb, true
+			}
+		}
+	}
+	return cur
+}
+
+/*
+===== EXPLORATION D'UN NIVEAU =====
+
+Recherche en largeur guidée par la distance, depuis entry, en gardant les
+ef meilleurs nœuds rencontrés. Retourne ces nœuds triés du plus proche au
+plus lointain.
+*/
+func (h *HNSW) searchLayer(vec []float32, entry int32, ef, level int) []candidate {
+	start := candidate{node: entry, dist: distance(vec, h.nodes[entry].vec)}
+	visited := map[int32]bool{entry: true}
+	toVisit := &candidateHeap{items: []candidate{start}}         // Tas min : prochain nœud à explorer
+	best := &candidateHeap{items: []candidate{start}, max: true} // Tas max : pire des ef gardés en racine
+
+	for toVisit.Len() > 0 {
+		c := heap.Pop(toVisit).(candidate)
+		if c.dist > best.items[0].dist && best.Len() >= ef {
+			break // Plus rien d'exploitable : tous les candidats restants sont plus loin
+		}
+		for _, nb := range h.nodes[c.node].links[level] {
+			if visited[nb] {
+				continue
+			}
+			visited[nb] = true
+			d := distance(vec, h.nodes[nb].vec)
+			if best.Len() < ef || d < best.items[0].dist {
+				heap.Push(toVisit, candidate{node: nb, dist: d})
+				heap.Push(best, candidate{node: nb, dist: d})
+				if best.Len() > ef {
+					heap.Pop(best)
+				}
+			}
+		}
+	}
+
+	out := best.items
+	sortCandidates(out)
+	return out
+}
+
+// candidate : un nœud du graphe et sa distance à la requête
+type candidate struct {
+	node int32
+	dist float32
+}
+
+func sortCandidates(cands []candidate) {
+	sort.Slice(cands, func(i, j int) bool {
+		if cands[i].dist != cands[j].dist {
+			return cands[i].dist < cands[j].dist
+		}
+		return cands[i].node < cands[j].node
+	})
+}
+
+// candidateHeap : tas de candidats, min (plus proche en racine) ou max (plus lointain en racine)
+type candidateHeap struct {
+	items []candidate
+	max   bool
+}
+
+func (c *candidateHeap) Len() int { return len(c.items) }
+func (c *candidateHeap) Less(i, j int) bool {
+	if c.max {
+		return c.items[i].dist > c.items[j].dist
+	}
+	return c.items[i].dist < c.items[j].dist
+}
+func (c *candidateHeap) Swap(i, j int) { c.items[i], c.items[j] = c.items[j], c.items[i] }
+func (c *candidateHeap) Push(x any)    { c.items = append(c.items, x.(candidate)) }
+func (c *candidateHeap) Pop() any {
+	old := c.items
+	x := old[len(old)-1]
+	c.items = old[:len(old)-1]
+	return x
+}
+
+/*
+===== FORMAT DE SAUVEGARDE =====
+
+Tout en little-endian :
+
+	magic "GISHNSW1"
+	en-tête : dim, M, EfConstruction, EfSearch, nombre de nœuds (uint32),
+	          point d'entrée (int32), niveau max (uint32)
+	nœuds   : id (int64), supprimé (uint8), niveau (uint32), vecteur (dim float32),
+	          puis pour chaque niveau : nombre de voisins (uint32), voisins (int32)
+*/
+const hnswMagic = "GISHNSW1"
+
+// Bornes des valeurs lues avant toute allocation (fichier corrompu)
+const (
+	maxHNSWDim   = 1 << 16
+	maxHNSWM     = 1 << 10
+	maxHNSWLevel = 64
+	// maxHNSWPrealloc borne la capacité initiale : au-delà, les nœuds sont ajoutés au fil de la lecture
+	maxHNSWPrealloc = 1 << 16
+)
+
+// Save écrit l'index complet (nœuds supprimés compris) dans w
+func (h *HNSW) Save(w io.Writer) error {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	bw := bufio.NewWriter(w)
+	le := binary.LittleEndian
+	b := []byte(hnswMagic)
+	for _, v := range []int{h.dim, h.cfg.M, h.cfg.EfConstruction, h.cfg.EfSearch, len(h.nodes)} {
+		b = le.AppendUint32(b, uint32(v))
+	}
+	b = le.AppendUint32(b, uint32(h.entry))
+	b = le.AppendUint32(b, uint32(h.maxLevel))
+	if _, err := bw.Write(b); err != nil {
+		return err
+	}
+
+	for _, n := range h.nodes {
+		b = le.AppendUint64(b[:0], uint64(n.id))
+		deleted := byte(0)
+		if n.deleted {
+			deleted = 1
+		}
+		b = append(b, deleted)
+		b = le.AppendUint32(b, uint32(len(n.links)-1))
+		for _, x := range n.vec {
+			b = le.AppendUint32(b, math.Float32bits(x))
+		}
+		for _, links := range n.links {
+			b = le.AppendUint32(b, uint32(len(links)))
+			for _, nb := range links {
+				b = le.AppendUint32(b, uint32(nb))
+			}
+		}
+		if _, err := bw.Write(b); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+/*
+===== CHARGEMENT =====
+
+Relit un index écrit par Save et vérifie la cohérence du graphe.
+La graine n'étant pas sauvegardée, les niveaux des prochains ajouts
+sont tirés avec seed.
+*/
+func LoadHNSW(r io.Reader, seed int64) (*HNSW, error) {
+	br := bufio.NewReader(r)
+	le := binary.LittleEndian
+
+	head := make([]byte, len(hnswMagic)+7*4)
+	if _, err := io.ReadFull(br, head); err != nil {
+		return nil, fmt.Errorf("%w : en-tête : %v", ErrHNSW, err)
+	}
+	if string(head[:len(hnswMagic)]) != hnswMagic {
+		return nil, fmt.Errorf("%w : signature inconnue", ErrHNSW)
+	}
+	u := func(i int) uint32 { return le.Uint32(head[len(hnswMagic)+4*i:]) }
+	if u(0) > maxHNSWDim || u(1) > maxHNSWM || u(6) > maxHNSWLevel {
+		return nil, fmt.Errorf("%w : en-tête hors limites (dim %d, M %d, niveau %d)", ErrHNSW, u(0), u(1), u(6))
+	}
+
+	h := NewHNSW(int(u(0)), HNSWConfig{
+		M: int(u(1)), EfConstruction: int(u(2)), EfSearch: int(u(3)), Seed: seed,
+	})
+	count := int(u(4))
+	h.entry = int32(u(5))
+	h.maxLevel = int(u(6))
+	if h.entry < -1 || int(h.entry) >= count || (count > 0) != (h.entry >= 0) {
+		return nil, fmt.Errorf("%w : point d'entrée %d hors limites", ErrHNSW, h.entry)
+	}
+
+	read := func(n int) ([]byte, error) {
+		buf := make([]byte, n)
+		if _, err := io.ReadFull(br, buf); err != nil {
+			return nil, fmt.Errorf("%w : nœud tronqué : %v", ErrHNSW, err)
+		}
+		return buf, nil
+	}
+
+	h.nodes = make([]hnswNode, 0, min(count, maxHNSWPrealloc))
+	for i := 0; i < count; i++ {
+		b, err := read(8 + 1 + 4 + 4*h.dim)
+		if err != nil {
+			return nil, err
+		}
+		n := hnswNode{id: int(int64(le.Uint64(b))), deleted: b[8] == 1}
+		level := int(le.Uint32(b[9:]))
+		if level > h.maxLevel {
+			return nil, fmt.Errorf("%w : nœud %d au niveau %d > %d", ErrHNSW, i, level, h.maxLevel)
+		}
+		n.vec = make([]float32, h.dim)
+		for j := range n.vec {
+			n.vec[j] = math.Float32frombits(le.Uint32(b[13+4*j:]))
+		}
+
+		n.links = make([][]int32, level+1)
+		for l := range n.links {
+			b, err := read(4)
+			if err != nil {
+				return nil, err
+			}
+			m := int(le.Uint32(b))
+			if m > count || m > h.maxLinks(l) {
+				return nil, fmt.Errorf("%w : nœud %d : %d voisins", ErrHNSW, i, m)
+			}
+			if b, err = read(4 * m); err != nil {
+				return nil, err
+			}
+			n.links[l] = make([]int32, m)
+			for j := range n.links[l] {
+				nb := int32(le.Uint32(b[4*j:]))
+				if nb < 0 || int(nb) >= count {
+					return nil, fmt.Errorf("%w : nœud %d : voisin %d hors limites", ErrHNSW, i, nb)
+				}
+				n.links[l][j] = nb
+			}
+		}
+
+		if !n.deleted {
+			h.ids[n.id] = int32(i)
+			h.live++
+		}
+		h.nodes = append(h.nodes, n)
+	}
+
+	// Un voisin au niveau l doit lui-même exister à ce niveau
+	for i, n := range h.nodes {
+		for l, links := range n.links {
+			for _, nb := range links {
+				if len(h.nodes[nb].links) <= l {
+					return nil, fmt.Errorf("%w : nœud %d : voisin %d absent du niveau %d", ErrHNSW, i, nb, l)
+				}
+			}
+		}
+	}
+	if h.entry >= 0 && len(h.nodes[h.entry].links) != h.maxLevel+1 {
+		return nil, fmt.Errorf("%w : point d'entrée hors du niveau %d", ErrHNSW, h.maxLevel)
+	}
+	return h, nil
+}
@@ -0,0 +1,112 @@
+package index
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+// Avec M = 1, le tirage des niveaux divisait par log(1) = 0 (panique à l'ajout)
+func TestHNSWMinimalM(t *testing.T) {
+	h := NewHNSW(2, HNSWConfig{M: 1})
+	for i := 0; i < 10; i++ {
+		if err := h.Add(i, []float32{float32(i), 0}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := h.Search([]float32{3, 0}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].ID != 3 {
+		t.Errorf("voisin %v, identifiant 3 attendu", got)
+	}
+}
+
+// testHNSW : index de n points (i, i²) indexés par i
+func testHNSW(t *testing.T, n int) *HNSW {
+	t.Helper()
+	h := NewHNSW(2, HNSWConfig{M: 4, Seed: 1})
+	for i := 0; i < n; i++ {
+		if err := h.Add(i, []float32{float32(i), float32(i * i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return h
+}
+
+// Un index relu donne les mêmes voisins, nœuds supprimés compris
+func TestHNSWSaveLoad(t *testing.T) {
+	h := testHNSW(t, 50)
+	if !h.Delete(7) || h.Delete(7) {
+		t.Fatal("Delete : true puis false attendus")
+	}
+
+	var buf bytes.Buffer
+	if err := h.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadHNSW(&buf, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Len() != 49 || loaded.Contains(7) || !loaded.Contains(8) {
+		t.Errorf("relu : %d vecteurs, contient 7 = %v, 8 = %v", loaded.Len(), loaded.Contains(7), loaded.Contains(8))
+	}
+
+	want, err := h.Search([]float32{7, 49}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := loaded.Search([]float32{7, 49}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("voisins relus %v, %v attendus", got, want)
+	}
+	for _, nb := range got {
+		if nb.ID == 7 {
+			t.Errorf("nœud supprimé 7 retourné : %v", got)
+		}
+	}
+}
+
+// Un fichier tronqué ou aux longueurs aberrantes est refusé sans allocation démesurée
+func TestHNSWLoadCorrupt(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testHNSW(t, 20).Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	header := len(hnswMagic) + 7*4
+
+	corrupt := func(field int, v uint32) []byte {
+		b := bytes.Clone(data)
+		binary.LittleEndian.PutUint32(b[len(hnswMagic)+4*field:], v)
+		return b
+	}
+	cases := map[string][]byte{
+		"tronqué":         data[:len(data)-3],
+		"en-tête tronqué": data[:header-1],
+		"signature":       append([]byte("GISHNSW0"), data[len(hnswMagic):]...),
+		"dimension":       corrupt(0, math.MaxUint32),
+		"M":               corrupt(1, math.MaxUint32),
+		"nombre de nœuds": corrupt(4, math.MaxUint32),
+		"niveau max":      corrupt(6, math.MaxUint32),
+		"nombre de voisins": func() []byte {
+			// Premier nœud : id, supprimé, niveau, vecteur, puis nombre de voisins du niveau 0
+			b := bytes.Clone(data)
+			binary.LittleEndian.PutUint32(b[header+8+1+4+4*2:], math.MaxUint32)
+			return b
+		}(),
+	}
+	for name, b := range cases {
+		if _, err := LoadHNSW(bytes.NewReader(b), 1); !errors.Is(err, ErrHNSW) {
+			t.Errorf("%s : erreur %v, ErrHNSW attendue", name, err)
+		}
+	}
+}
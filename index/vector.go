@@ -0,0 +1,112 @@
+package index
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/MrIsmail1/Golang_images_matcher/model"
+)
+
+/*
+===== VECTEUR DE CARACTÉRISTIQUES D'UNE IMAGE =====
+
+À QUOI ÇA SERT :
+Aplatit un descripteur en un vecteur de flottants de longueur fixe, pour
+l'index de plus proches voisins approché (HNSW) : la distance euclidienne
+entre deux vecteurs approche la dissemblance des deux images.
+
+CONTENU (dans cet ordre) :
+1. Histogrammes globaux RGB puis HSV (6 × bins valeurs)
+2. Couleur moyenne globale (3 valeurs)
+3. Texture globale puis forme globale (2 valeurs)
+4. Optionnellement, pour chaque tuile : couleur moyenne, texture, forme
+
+NORMALISATION :
+Chaque bloc est ramené à une norme d'au plus 1, pour qu'aucune
+caractéristique n'écrase les autres. Les histogrammes sont normalisés
+(somme 1) puis passés à la racine carrée : la distance euclidienne entre
+deux histogrammes devient la distance de Hellinger, insensible à la
+taille de l'image.
+*/
+type VectorOptions struct {
+	// Tiles : ajoute le résumé de chaque tuile (couleur moyenne, texture, forme)
+	Tiles bool
+}
+
+// Bornes des signatures scalaires (voir analyser-utils/texture et shape)
+const (
+	maxTexture = 510.0 // Somme de deux différences de niveaux de gris
+	maxShape   = 1.0   // Proportion de pixels de contour
+)
+
+// VectorLen retourne la longueur des vecteurs produits pour ces paramètres d'analyse
+func VectorLen(params model.AnalysisParams, opts VectorOptions) int {
+	n := 6*params.Bins + 3 + 2
+	if opts.Tiles {
+		n += params.TilesPerRow * params.TilesPerRow * 5
+	}
+	return n
+}
+
+/*
+===== APLATISSEMENT D'UN DESCRIPTEUR =====
+
+Retourne une erreur si la forme du descripteur ne correspond pas à ses
+paramètres d'analyse (histogrammes ou tuiles manquants).
+*/
+func FlattenDescriptor(desc *model.FullImageDescriptor, opts VectorOptions) ([]float32, error) {
+	if err := model.CheckDescriptor(desc); err != nil {
+		return nil, err
+	}
+
+	vec := make([]float32, 0, VectorLen(desc.Params, opts))
+
+	// Blocs histogrammes : 3 canaux de norme 1 chacun, divisés par √3
+	for _, key := range []string{"r", "g", "b"} {
+		vec = appendHellinger(vec, desc.GlobalRGB[key], 1/math.Sqrt(3))
+	}
+	for _, key := range []string{"h", "s", "v"} {
+		vec = appendHellinger(vec, desc.GlobalHSV[key], 1/math.Sqrt(3))
+	}
+
+	vec = appendSummary(vec, desc.GlobalMeanColor, desc.GlobalTexture, desc.GlobalShape, 1)
+
+	if opts.Tiles {
+		// Toutes les tuiles réunies pèsent autant qu'un résumé global
+		scale := 1 / math.Sqrt(float64(len(desc.Tiles)))
+		for _, t := range desc.Tiles {
+			vec = appendSummary(vec, t.MeanColor, t.TextureSignature, t.ShapeSignature, scale)
+		}
+	}
+
+	if len(vec) != VectorLen(desc.Params, opts) {
+		return nil, fmt.Errorf("index: vecteur de %d valeurs, %d attendues", len(vec), VectorLen(desc.Params, opts))
+	}
+	return vec, nil
+}
+
+// appendHellinger ajoute la racine de l'histogramme normalisé (somme 1), multipliée par scale
+func appendHellinger(vec []float32, hist []int, scale float64) []float32 {
+	total := 0
+	for _, v := range hist {
+		total += v
+	}
+	for _, v := range hist {
+		x := 0.0
+		if total > 0 {
+			x = math.Sqrt(float64(v)/float64(total)) * scale
+		}
+		vec = append(vec, float32(x))
+	}
+	return vec
+}
+
+// appendSummary ajoute couleur moyenne, texture et forme ramenées dans [0, 1], multipliées par scale
+func appendSummary(vec []float32, mean [3]float64, texture, shape, scale float64) []float32 {
+	for _, c := range mean {
+		vec = append(vec, float32(c/255/math.Sqrt(3)*scale))
+	}
+	return append(vec,
+		float32(texture/maxTexture*scale),
+		float32(shape/maxShape*scale))
+}
@@ -0,0 +1,61 @@
+package testbank
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/MrIsmail1/Golang_images_matcher/analyzer"
+	"github.com/MrIsmail1/Golang_images_matcher/model"
+)
+
+/*
+===== IMAGES DE LA BANQUE POUR LES TESTS =====
+
+À QUOI ÇA SERT :
+Fixture commune aux tests des paquets compare, search et analyser-utils :
+le dossier banque/images du dépôt, quel que soit le paquet qui l'utilise,
+et l'analyse de quelques-unes de ses images. Un test sans banque (copie
+partielle du dépôt) est ignoré plutôt qu'en échec.
+*/
+
+// Dir : dossier des images de la banque (chemin absolu)
+var Dir = func() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "..", "..", "banque", "images")
+}()
+
+// Require ignore le test si la banque est absente
+func Require(t testing.TB) {
+	t.Helper()
+	if _, err := os.Stat(Dir); err != nil {
+		t.Skipf("banque absente : %v", err)
+	}
+}
+
+/*
+===== DESCRIPTEURS DE QUELQUES IMAGES =====
+
+Analyse chaque image avec params. Chaque chemin est relatif à Dir, ou
+complet (résultat de search.ListImages(Dir) par exemple).
+*/
+func Descriptors(t testing.TB, params model.AnalysisParams, paths ...string) []*model.FullImageDescriptor {
+	t.Helper()
+	Require(t)
+	descs := make([]*model.FullImageDescriptor, len(paths))
+	for i, path := range paths {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(Dir, path)
+		}
+		if _, err := os.Stat(path); err != nil {
+			t.Skipf("image de la banque absente : %v", err)
+		}
+		desc, err := analyzer.AnalyzeImageWith(path, params)
+		if err != nil {
+			t.Fatalf("%s : %v", path, err)
+		}
+		descs[i] = desc
+	}
+	return descs
+}
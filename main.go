@@ -2,97 +2,82 @@ package main
 
 import (
 	"fmt"
-
-	"github.com/MrIsmail1/Golang_images_matcher/analyzer"
-	"github.com/MrIsmail1/Golang_images_matcher/compare"
-	"github.com/MrIsmail1/Golang_images_matcher/model"
-
 	"os"
-	"path/filepath"
-	"strings"
 )
 
 /*
 ===== PROGRAMME PRINCIPAL DE RECONNAISSANCE D'IMAGES =====
 
 À QUOI ÇA SERT :
-Démonstrateur complet du système ! Trouve l'image la plus similaire dans une base
-de données à partir d'une image de requête.
-*/
-func main() {
-
-	// Image cible à analyser (pourrait venir d'arguments CLI)
-	imageName := "chien13.png"
-	imagePath := "banque/images/" + imageName
-
-	// Construction du chemin du cache JSON correspondant
-	// strings.TrimSuffix enlève l'extension, puis on ajoute .json
-	jsonTarget := "banque/json/" + strings.TrimSuffix(imageName, filepath.Ext(imageName)) + ".json"
+Interface en ligne de commande du système ! Trouve les images les plus similaires
+dans une banque d'images à partir d'une ou plusieurs images de requête.
 
-	var desc *model.FullImageDescriptor
+SOUS-COMMANDES :
+- index   : génère les descripteurs JSON de toutes les images d'un dossier
+- search  : recherche les images les plus proches d'une ou plusieurs requêtes
+- inspect : affiche le contenu d'un descripteur
+- convert : convertit la banque entre JSON et index binaire
+- check   : vérifications de non-régression du score sur la banque
 
-	// Vérification de l'existence du cache JSON
-	// os.Stat retourne une erreur si le fichier n'existe pas
-	if _, err := os.Stat(jsonTarget); os.IsNotExist(err) {
+EXEMPLES :
 
-		fmt.Println("🔧 Descripteur non trouvé, génération en cours...")
-
-		// Analyse complète de l'image (opération coûteuse 1-3 secondes)
-		d, err := analyzer.AnalyzeImage(imagePath)
-		if err != nil {
-			fmt.Println("Erreur analyse:", err)
-			return // Arrêt du programme en cas d'erreur critique
-		}
-
-		// Sauvegarde du descripteur en cache pour les prochaines fois
-		model.SaveDescriptor(d, jsonTarget)
-		desc = d
-		fmt.Println("✅ Descripteur généré :", jsonTarget)
-
-	} else {
-
-		fmt.Println("✅ Descripteur existant :", jsonTarget)
+	go run . index -bank banque/images -cache banque/json
+	go run . search -query banque/images/chien13.png
+	go run . inspect -query banque/images/chien13.png
+*/
+func main() {
 
-		// Chargement ultra-rapide depuis le JSON (quelques millisecondes)
-		desc = model.LoadDescriptor(jsonTarget)
+	// Sans sous-commande, on affiche l'aide et on sort en erreur
+	if len(os.Args) < 2 {
+		printUsage()
+		os.Exit(2)
 	}
 
-	// Recherche de tous les fichiers JSON dans la banque
-	bankFiles, _ := filepath.Glob("banque/json/*.json")
-
-	// Variables pour tracker le meilleur match
-	bestMatch := ""
-	bestScore := 0.0
-
-	// Parcours de tous les descripteurs de la banque
-	for _, file := range bankFiles {
-
-		// Auto-exclusion : évite de comparer l'image avec elle-même
-		// PRINCIPE : Si même nom de fichier → skip cette comparaison
-		if filepath.Base(file) == filepath.Base(jsonTarget) {
-			continue // Passe au fichier suivant
-		}
+	var err error
+
+	// Aiguillage vers la sous-commande demandée
+	// Chaque sous-commande possède son propre jeu de flags
+	switch os.Args[1] {
+	case "index":
+		err = runIndex(os.Args[2:])
+	case "search":
+		err = runSearch(os.Args[2:])
+	case "inspect":
+		err = runInspect(os.Args[2:])
+	case "convert":
+		err = runConvert(os.Args[2:])
+	case "check":
+		err = runCheck(os.Args[2:])
+	case "help", "-h", "-help", "--help":
+		printUsage()
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "Sous-commande inconnue : %q\n\n", os.Args[1])
+		printUsage()
+		os.Exit(2)
+	}
 
-		// Chargement du descripteur de cette image de la banque
-		bankDesc := model.LoadDescriptor(file)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "❌ Erreur :", err)
+		os.Exit(1)
+	}
+}
 
-		// Calcul du score de similarité (cœur du système !)
-		score := compare.CompareDescriptors(desc, bankDesc)
+/*
+===== AIDE GÉNÉRALE =====
 
-		// Affichage du résultat de cette comparaison
-		fmt.Printf("🔹 %s : %.2f%% de similarité\n", bankDesc.ImageName, score)
+Affiche la liste des sous-commandes disponibles.
+Le détail des flags s'obtient avec : <sous-commande> -h
+*/
+func printUsage() {
+	fmt.Fprintln(os.Stderr, `Usage : Golang_images_matcher <sous-commande> [flags]
 
-		// Mise à jour du meilleur match si ce score est supérieur
-		if score > bestScore {
-			bestScore = score
-			bestMatch = bankDesc.ImageName
-		}
-	}
+Sous-commandes :
+  index    Génère les descripteurs de toutes les images de la banque
+  search   Recherche les images les plus similaires à une ou plusieurs requêtes
+  inspect  Affiche le descripteur d'une image
+  convert  Convertit la banque entre JSON et index binaire
+  check    Vérifie le score : 100 % sur soi-même, copies classées en tête
 
-	// Annonce du gagnant ou d'échec
-	if bestMatch != "" {
-		fmt.Printf("\n🏆 Meilleure correspondance : %s avec %.2f%%\n", bestMatch, bestScore)
-	} else {
-		fmt.Println("❌ Aucune correspondance trouvée.")
-	}
+Utilisez "<sous-commande> -h" pour la liste des flags.`)
 }
@@ -0,0 +1,706 @@
+package model
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+/*
+===== INDEX BINAIRE MONO-FICHIER =====
+
+À QUOI ÇA SERT :
+Stocke toute une banque de descripteurs dans UN seul fichier compact, au lieu
+d'un JSON indenté par image. Charger la banque ne coûte plus des milliers
+d'ouvertures de fichiers ni de parsing JSON.
+
+FORMAT (entiers en little-endian) :
+
+	EN-TÊTE
+	  magic "GISBIDX1"            8 octets
+	  version du format           uint16
+	  version du schéma           uint16
+	  paramètres d'analyse        longueur uint32 + JSON (AnalysisParams)
+	ENREGISTREMENTS (un par image, les uns à la suite des autres)
+	  longueur des métadonnées    uint32 (> 0)
+	  métadonnées JSON            nom de l'image, empreinte source
+	  bloc global                 voir ci-dessous
+	  bloc de chaque tuile        grille² blocs
+	  blocs de la pyramide        grille² blocs par niveau (depuis la version 4)
+	FIN DES ENREGISTREMENTS       uint32 = 0
+	TABLE DES OFFSETS             nombre × uint64 (début de chaque enregistrement)
+	PIED DE PAGE
+	  nombre d'enregistrements    uint32
+	  offset de la table          uint64
+	  magic "GISBIDX1"            8 octets
+
+BLOC (image globale ou tuile), largeur FIXE :
+- 6 histogrammes (r, g, b, h, s, v) × bins × uint32
+- pHash uint64
+- couleur moyenne 3 × float64, texture float64, forme float64
+- couverture alpha float64 (depuis la version 3)
+
+VERSIONS DU FORMAT :
+- 1 : paramètres en 3 × uint32 (taille, bins, grille), images étirées
+- 2 : paramètres en JSON, mode de normalisation compris
+- 3 : couverture alpha dans chaque bloc
+- 4 : niveaux de la pyramide spatiale après les tuiles
+Les index v1 et v2 restent lisibles (images supposées opaques), ainsi
+que les index v3 (sans pyramide).
+
+LECTURES :
+- Accès direct : OpenBinaryIndex lit le pied de page puis la table des
+  offsets, et décode n'importe quel enregistrement à la demande (fichier
+  projeté en mémoire quand le système le permet)
+- Flux : ReadBinaryIndex lit les enregistrements dans l'ordre depuis un
+  io.Reader quelconque, sans avoir besoin de la table
+*/
+
+// binaryMagic identifie les fichiers d'index binaire
+const binaryMagic = "GISBIDX1"
+
+// BinaryIndexVersion : version du format binaire écrit par ce programme
+const BinaryIndexVersion = 4
+
+// Ordre des histogrammes dans un bloc
+var (
+	rgbKeys = [3]string{"r", "g", "b"}
+	hsvKeys = [3]string{"h", "s", "v"}
+)
+
+// Tailles fixes du début de l'en-tête (magic, versions) et du pied de page
+const (
+	binaryPrefixSize = 8 + 2 + 2
+	binaryFooterSize = 4 + 8 + 8
+)
+
+// maxParamsSize borne les paramètres JSON de l'en-tête (fichier corrompu)
+const maxParamsSize = 1 << 16
+
+// maxMetaSize borne les métadonnées JSON d'un enregistrement lu en flux (fichier corrompu)
+const maxMetaSize = 1 << 16
+
+// ErrBinaryIndex : fichier d'index binaire invalide ou corrompu
+var ErrBinaryIndex = errors.New("index binaire invalide")
+
+/*
+===== MÉTADONNÉES D'UN ENREGISTREMENT =====
+
+Partie de taille variable, encodée en JSON car peu volumineuse.
+*/
+type binaryMeta struct {
+	ImageName string      `json:"image_name"`
+	Source    *SourceInfo `json:"source,omitempty"`
+}
+
+// blockSize : taille en octets d'un bloc (alpha : avec la couverture alpha, version ≥ 3)
+func blockSize(bins int, alpha bool) int {
+	if alpha {
+		return 6*bins*4 + 8 + 6*8
+	}
+	return 6*bins*4 + 8 + 5*8
+}
+
+// recordFixedSize : taille de la partie fixe d'un enregistrement
+func recordFixedSize(p AnalysisParams, alpha bool) int {
+	blocks := 1 + p.TilesPerRow*p.TilesPerRow
+	for _, g := range p.PyramidGrids() {
+		blocks += g * g
+	}
+	return blocks * blockSize(p.Bins, alpha)
+}
+
+// ==============================================================================================
+// ÉCRITURE
+// ==============================================================================================
+
+/*
+===== ÉCRIVAIN D'INDEX BINAIRE =====
+
+À QUOI ÇA SERT :
+Écrit un index en flux : les descripteurs sont ajoutés un par un (pas besoin
+de toute la banque en mémoire), la table des offsets est écrite à Close.
+
+USAGE :
+
+	w, _ := model.NewBinaryIndexWriter(file, model.CurrentParams())
+	for ... { w.Add(desc) }
+	w.Close()
+*/
+type BinaryIndexWriter struct {
+	w       *bufio.Writer
+	params  AnalysisParams
+	offset  uint64   // Position courante dans le flux
+	offsets []uint64 // Début de chaque enregistrement
+	buf     []byte   // Tampon réutilisé pour la partie fixe
+}
+
+// NewBinaryIndexWriter écrit l'en-tête et prépare l'ajout d'enregistrements
+func NewBinaryIndexWriter(w io.Writer, params AnalysisParams) (*BinaryIndexWriter, error) {
+	if err := params.Validate(); err != nil {
+		return nil, fmt.Errorf("%w : %v", ErrBinaryIndex, err)
+	}
+	paramsJSON, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+
+	bw := &BinaryIndexWriter{
+		w:      bufio.NewWriter(w),
+		params: params,
+		buf:    make([]byte, recordFixedSize(params, true)),
+	}
+
+	header := make([]byte, 0, binaryPrefixSize+4+len(paramsJSON))
+	header = append(header, binaryMagic...)
+	header = binary.LittleEndian.AppendUint16(header, BinaryIndexVersion)
+	header = binary.LittleEndian.AppendUint16(header, SchemaVersion)
+	header = binary.LittleEndian.AppendUint32(header, uint32(len(paramsJSON)))
+	header = append(header, paramsJSON...)
+
+	if err := bw.write(header); err != nil {
+		return nil, err
+	}
+	return bw, nil
+}
+
+/*
+===== AJOUT D'UN DESCRIPTEUR =====
+
+Le descripteur doit avoir été produit avec les paramètres de l'index
+(même nombre de bins et de tuiles), sinon il est refusé.
+*/
+func (bw *BinaryIndexWriter) Add(desc *FullImageDescriptor) error {
+	if desc.Params != bw.params {
+		return &IncompatibleError{Reason: fmt.Sprintf(
+			"%s : paramètres %+v, %+v attendus par l'index", desc.ImageName, desc.Params, bw.params)}
+	}
+	if err := checkShape(desc); err != nil {
+		return err
+	}
+
+	meta, err := json.Marshal(binaryMeta{ImageName: desc.ImageName, Source: desc.Source})
+	if err != nil {
+		return err
+	}
+
+	// Encodage de la partie fixe : bloc global, blocs des tuiles puis des niveaux de la pyramide
+	b := appendBlock(bw.buf[:0], desc.GlobalRGB, desc.GlobalHSV, desc.GlobalPHash,
+		desc.GlobalMeanColor, desc.GlobalTexture, desc.GlobalShape, desc.GlobalAlphaCoverage)
+	b = appendTiles(b, desc.Tiles)
+	for _, level := range desc.Levels {
+		b = appendTiles(b, level.Tiles)
+	}
+
+	bw.offsets = append(bw.offsets, bw.offset)
+	if err := bw.write(binary.LittleEndian.AppendUint32(nil, uint32(len(meta)))); err != nil {
+		return err
+	}
+	if err := bw.write(meta); err != nil {
+		return err
+	}
+	return bw.write(b)
+}
+
+/*
+===== FIN DE L'INDEX =====
+
+Écrit le marqueur de fin, la table des offsets et le pied de page.
+Ne ferme pas le io.Writer sous-jacent.
+*/
+func (bw *BinaryIndexWriter) Close() error {
+	if err := bw.write(binary.LittleEndian.AppendUint32(nil, 0)); err != nil {
+		return err
+	}
+
+	tableOffset := bw.offset
+	table := make([]byte, 0, 8*len(bw.offsets))
+	for _, off := range bw.offsets {
+		table = binary.LittleEndian.AppendUint64(table, off)
+	}
+	if err := bw.write(table); err != nil {
+		return err
+	}
+
+	footer := make([]byte, 0, binaryFooterSize)
+	footer = binary.LittleEndian.AppendUint32(footer, uint32(len(bw.offsets)))
+	footer = binary.LittleEndian.AppendUint64(footer, tableOffset)
+	footer = append(footer, binaryMagic...)
+	if err := bw.write(footer); err != nil {
+		return err
+	}
+	return bw.w.Flush()
+}
+
+// write écrit des octets et avance la position courante
+func (bw *BinaryIndexWriter) write(b []byte) error {
+	n, err := bw.w.Write(b)
+	bw.offset += uint64(n)
+	return err
+}
+
+// appendTiles encode les blocs d'une grille de tuiles, dans l'ordre
+func appendTiles(b []byte, tiles []TileDescriptor) []byte {
+	for _, t := range tiles {
+		b = appendBlock(b, t.HistogramRGB, t.HistogramHSV, t.PHash,
+			t.MeanColor, t.TextureSignature, t.ShapeSignature, t.AlphaCoverage)
+	}
+	return b
+}
+
+// appendBlock encode un bloc (global ou tuile) à largeur fixe
+func appendBlock(b []byte, rgb, hsv map[string][]int, phash PHash,
+	mean [3]float64, texture, shape, alpha float64) []byte {
+
+	for _, k := range rgbKeys {
+		for _, v := range rgb[k] {
+			b = binary.LittleEndian.AppendUint32(b, uint32(v))
+		}
+	}
+	for _, k := range hsvKeys {
+		for _, v := range hsv[k] {
+			b = binary.LittleEndian.AppendUint32(b, uint32(v))
+		}
+	}
+
+	b = binary.LittleEndian.AppendUint64(b, uint64(phash))
+
+	for _, f := range [6]float64{mean[0], mean[1], mean[2], texture, shape, alpha} {
+		b = binary.LittleEndian.AppendUint64(b, math.Float64bits(f))
+	}
+	return b
+}
+
+// ==============================================================================================
+// LECTURE
+// ==============================================================================================
+
+/*
+===== INDEX BINAIRE OUVERT EN ACCÈS DIRECT =====
+
+Les enregistrements sont décodés à la demande : seule la table des offsets
+est gardée en mémoire. Utilisable depuis plusieurs goroutines.
+*/
+type BinaryIndex struct {
+	r       io.ReaderAt
+	closer  io.Closer
+	header  binaryHeader
+	offsets []uint64
+	end     int64 // Position du marqueur de fin des enregistrements
+}
+
+/*
+===== OUVERTURE D'UN INDEX BINAIRE =====
+
+Projette le fichier en mémoire (mmap) quand le système le permet,
+sinon lit les enregistrements avec ReadAt.
+*/
+func OpenBinaryIndex(path string) (*BinaryIndex, error) {
+	r, closer, size, err := openReaderAt(path)
+	if err != nil {
+		return nil, err
+	}
+
+	idx, err := newBinaryIndex(r, size)
+	if err != nil {
+		closer.Close()
+		return nil, fmt.Errorf("%s : %w", path, err)
+	}
+	idx.closer = closer
+	return idx, nil
+}
+
+// newBinaryIndex lit l'en-tête, le pied de page et la table des offsets
+func newBinaryIndex(r io.ReaderAt, size int64) (*BinaryIndex, error) {
+	if size < binaryPrefixSize+4+binaryFooterSize {
+		return nil, fmt.Errorf("%w : fichier trop court", ErrBinaryIndex)
+	}
+
+	h, err := readHeader(io.NewSectionReader(r, 0, size-binaryFooterSize))
+	if err != nil {
+		return nil, err
+	}
+
+	footer := make([]byte, binaryFooterSize)
+	if _, err := r.ReadAt(footer, size-binaryFooterSize); err != nil {
+		return nil, err
+	}
+	if string(footer[12:]) != binaryMagic {
+		return nil, fmt.Errorf("%w : pied de page absent (fichier tronqué ?)", ErrBinaryIndex)
+	}
+	count := int64(binary.LittleEndian.Uint32(footer[0:4]))
+	tableOffset := int64(binary.LittleEndian.Uint64(footer[4:12]))
+	if tableOffset < h.size || tableOffset+8*count != size-binaryFooterSize {
+		return nil, fmt.Errorf("%w : table des offsets incohérente", ErrBinaryIndex)
+	}
+
+	table := make([]byte, 8*count)
+	if _, err := r.ReadAt(table, tableOffset); err != nil {
+		return nil, err
+	}
+	offsets := make([]uint64, count)
+	for i := range offsets {
+		offsets[i] = binary.LittleEndian.Uint64(table[8*i:])
+	}
+
+	return &BinaryIndex{r: r, header: h, offsets: offsets, end: tableOffset - 4}, nil
+}
+
+// Len retourne le nombre de descripteurs de l'index
+func (idx *BinaryIndex) Len() int {
+	return len(idx.offsets)
+}
+
+// Params retourne les paramètres d'analyse communs à tout l'index
+func (idx *BinaryIndex) Params() AnalysisParams {
+	return idx.header.params
+}
+
+/*
+===== DESCRIPTEUR N° i =====
+
+Décode un enregistrement à partir de son offset (accès direct).
+*/
+func (idx *BinaryIndex) Descriptor(i int) (*FullImageDescriptor, error) {
+	off, metaLen, err := idx.recordMeta(i)
+	if err != nil {
+		return nil, err
+	}
+
+	record := make([]byte, metaLen+recordFixedSize(idx.header.params, idx.header.alpha()))
+	if _, err := idx.r.ReadAt(record, off+4); err != nil {
+		return nil, err
+	}
+	return decodeRecord(record[:metaLen], record[metaLen:], idx.header)
+}
+
+/*
+===== EMPREINTE DU DESCRIPTEUR N° i =====
+
+Ne décode que les métadonnées de l'enregistrement, sans ses histogrammes :
+de quoi trier les enregistrements sans charger tout l'index.
+*/
+func (idx *BinaryIndex) Source(i int) (*SourceInfo, error) {
+	m, _, err := idx.readMeta(i)
+	if err != nil {
+		return nil, err
+	}
+	return m.Source, nil
+}
+
+/*
+===== RÉSUMÉ DU DESCRIPTEUR N° i =====
+
+Descripteur réduit au nom, à la source, aux paramètres et au pHash global,
+sans histogrammes ni tuiles : de quoi construire le pré-filtre pHash d'une
+grosse banque sans décoder tous ses enregistrements.
+*/
+func (idx *BinaryIndex) Summary(i int) (*FullImageDescriptor, error) {
+	m, fixedOff, err := idx.readMeta(i)
+	if err != nil {
+		return nil, err
+	}
+
+	// Le pHash suit les histogrammes RGB et HSV du bloc global
+	var phash [8]byte
+	if _, err := idx.r.ReadAt(phash[:], fixedOff+int64(6*idx.header.params.Bins*4)); err != nil {
+		return nil, err
+	}
+	return &FullImageDescriptor{
+		SchemaVersion: SchemaVersion,
+		Params:        idx.header.params,
+		ImageName:     m.ImageName,
+		Source:        m.Source,
+		GlobalPHash:   PHash(binary.LittleEndian.Uint64(phash[:])),
+	}, nil
+}
+
+// readMeta décode les métadonnées de l'enregistrement n° i et retourne l'offset de sa partie fixe
+func (idx *BinaryIndex) readMeta(i int) (binaryMeta, int64, error) {
+	var m binaryMeta
+	off, metaLen, err := idx.recordMeta(i)
+	if err != nil {
+		return m, 0, err
+	}
+
+	meta := make([]byte, metaLen)
+	if _, err := idx.r.ReadAt(meta, off+4); err != nil {
+		return m, 0, err
+	}
+	if err := json.Unmarshal(meta, &m); err != nil {
+		return m, 0, fmt.Errorf("%w : métadonnées : %v", ErrBinaryIndex, err)
+	}
+	return m, off + 4 + int64(metaLen), nil
+}
+
+/*
+recordMeta retourne l'offset de l'enregistrement n° i et la longueur de ses
+métadonnées, vérifiée avant toute allocation : l'enregistrement doit tenir
+avant le suivant (ou avant le marqueur de fin pour le dernier).
+*/
+func (idx *BinaryIndex) recordMeta(i int) (int64, int, error) {
+	if i < 0 || i >= len(idx.offsets) {
+		return 0, 0, fmt.Errorf("%w : enregistrement %d hors limites", ErrBinaryIndex, i)
+	}
+	off := int64(idx.offsets[i])
+	next := idx.end
+	if i+1 < len(idx.offsets) {
+		next = int64(idx.offsets[i+1])
+	}
+
+	var lenBuf [4]byte
+	if _, err := idx.r.ReadAt(lenBuf[:], off); err != nil {
+		return 0, 0, err
+	}
+	metaLen := int64(binary.LittleEndian.Uint32(lenBuf[:]))
+	fixed := int64(recordFixedSize(idx.header.params, idx.header.alpha()))
+	if off < idx.header.size || 4+metaLen+fixed > next-off {
+		return 0, 0, fmt.Errorf("%w : enregistrement %d de taille incohérente", ErrBinaryIndex, i)
+	}
+	return off, int(metaLen), nil
+}
+
+// Close libère le fichier (et la projection mémoire)
+func (idx *BinaryIndex) Close() error {
+	if idx.closer == nil {
+		return nil
+	}
+	return idx.closer.Close()
+}
+
+/*
+===== LECTURE EN FLUX =====
+
+À QUOI ÇA SERT :
+Parcourt les enregistrements dans l'ordre depuis n'importe quel io.Reader
+(tube, réseau, fichier compressé...), sans accès direct ni table des offsets.
+Le parcours s'arrête à la première erreur retournée par fn.
+*/
+func ReadBinaryIndex(r io.Reader, fn func(*FullImageDescriptor) error) error {
+	br := bufio.NewReader(r)
+
+	h, err := readHeader(br)
+	if err != nil {
+		return err
+	}
+
+	fixed := make([]byte, recordFixedSize(h.params, h.alpha()))
+	var lenBuf [4]byte
+	for {
+		if _, err := io.ReadFull(br, lenBuf[:]); err != nil {
+			return fmt.Errorf("%w : enregistrement tronqué : %v", ErrBinaryIndex, err)
+		}
+		metaLen := binary.LittleEndian.Uint32(lenBuf[:])
+		if metaLen == 0 {
+			return nil // Marqueur de fin des enregistrements
+		}
+		if metaLen > maxMetaSize {
+			return fmt.Errorf("%w : métadonnées de %d octets", ErrBinaryIndex, metaLen)
+		}
+
+		meta := make([]byte, metaLen)
+		if _, err := io.ReadFull(br, meta); err != nil {
+			return fmt.Errorf("%w : enregistrement tronqué : %v", ErrBinaryIndex, err)
+		}
+		if _, err := io.ReadFull(br, fixed); err != nil {
+			return fmt.Errorf("%w : enregistrement tronqué : %v", ErrBinaryIndex, err)
+		}
+
+		desc, err := decodeRecord(meta, fixed, h)
+		if err != nil {
+			return err
+		}
+		if err := fn(desc); err != nil {
+			return err
+		}
+	}
+}
+
+/*
+===== EN-TÊTE D'UN INDEX LU =====
+
+Paramètres d'analyse communs à tout l'index, version du format (qui fixe
+la largeur des blocs) et taille totale de l'en-tête.
+*/
+type binaryHeader struct {
+	params  AnalysisParams
+	version uint16
+	size    int64
+}
+
+// alpha indique si les blocs contiennent la couverture alpha
+func (h binaryHeader) alpha() bool {
+	return h.version >= 3
+}
+
+// readHeader vérifie la signature et les versions, puis lit les paramètres d'analyse
+func readHeader(r io.Reader) (binaryHeader, error) {
+	prefix := make([]byte, binaryPrefixSize)
+	if _, err := io.ReadFull(r, prefix); err != nil {
+		return binaryHeader{}, fmt.Errorf("%w : en-tête : %v", ErrBinaryIndex, err)
+	}
+	if string(prefix[:8]) != binaryMagic {
+		return binaryHeader{}, fmt.Errorf("%w : signature inconnue", ErrBinaryIndex)
+	}
+	h := binaryHeader{
+		version: binary.LittleEndian.Uint16(prefix[8:]),
+		size:    binaryPrefixSize,
+	}
+	schema := int(binary.LittleEndian.Uint16(prefix[10:]))
+
+	switch {
+	case h.version == 1:
+		// Schéma v1 : paramètres à largeur fixe
+		if schema != 1 {
+			return binaryHeader{}, &IncompatibleError{Reason: fmt.Sprintf(
+				"index v1 au schéma v%d", schema)}
+		}
+		fixed := make([]byte, 3*4)
+		if _, err := io.ReadFull(r, fixed); err != nil {
+			return binaryHeader{}, fmt.Errorf("%w : en-tête : %v", ErrBinaryIndex, err)
+		}
+		h.params = AnalysisParams{
+			StandardSize: int(binary.LittleEndian.Uint32(fixed[0:])),
+			Bins:         int(binary.LittleEndian.Uint32(fixed[4:])),
+			TilesPerRow:  int(binary.LittleEndian.Uint32(fixed[8:])),
+		}
+		h.size += int64(len(fixed))
+
+	case h.version <= BinaryIndexVersion:
+		if schema < 2 || schema > SchemaVersion {
+			return binaryHeader{}, &IncompatibleError{Reason: fmt.Sprintf(
+				"index au schéma v%d, v%d attendu", schema, SchemaVersion)}
+		}
+		var lenBuf [4]byte
+		if _, err := io.ReadFull(r, lenBuf[:]); err != nil {
+			return binaryHeader{}, fmt.Errorf("%w : en-tête : %v", ErrBinaryIndex, err)
+		}
+		n := binary.LittleEndian.Uint32(lenBuf[:])
+		if n > maxParamsSize {
+			return binaryHeader{}, fmt.Errorf("%w : paramètres de %d octets", ErrBinaryIndex, n)
+		}
+		paramsJSON := make([]byte, n)
+		if _, err := io.ReadFull(r, paramsJSON); err != nil {
+			return binaryHeader{}, fmt.Errorf("%w : en-tête : %v", ErrBinaryIndex, err)
+		}
+		if err := json.Unmarshal(paramsJSON, &h.params); err != nil {
+			return binaryHeader{}, fmt.Errorf("%w : paramètres : %v", ErrBinaryIndex, err)
+		}
+		h.size += int64(len(lenBuf) + len(paramsJSON))
+
+	default:
+		return binaryHeader{}, fmt.Errorf("%w : format v%d non supporté", ErrBinaryIndex, h.version)
+	}
+
+	// Index d'une version antérieure : champs absents à leur valeur historique
+	h.params = UpgradeParams(h.params)
+	if err := h.params.Validate(); err != nil {
+		return binaryHeader{}, fmt.Errorf("%w : %v", ErrBinaryIndex, err)
+	}
+	if h.version < 4 && h.params.Pyramid != "" {
+		return binaryHeader{}, fmt.Errorf("%w : pyramide dans un index v%d", ErrBinaryIndex, h.version)
+	}
+	return h, nil
+}
+
+// decodeRecord reconstruit un descripteur à partir de ses métadonnées et de sa partie fixe
+func decodeRecord(meta, fixed []byte, h binaryHeader) (*FullImageDescriptor, error) {
+	params, alpha := h.params, h.alpha()
+
+	var m binaryMeta
+	if err := json.Unmarshal(meta, &m); err != nil {
+		return nil, fmt.Errorf("%w : métadonnées : %v", ErrBinaryIndex, err)
+	}
+
+	desc := &FullImageDescriptor{
+		SchemaVersion: SchemaVersion,
+		Params:        params,
+		ImageName:     m.ImageName,
+		Source:        m.Source,
+	}
+
+	bs := blockSize(params.Bins, alpha)
+	g := readBlock(fixed[:bs], params.Bins, alpha)
+	desc.GlobalRGB, desc.GlobalHSV, desc.GlobalPHash = g.rgb, g.hsv, g.phash
+	desc.GlobalMeanColor, desc.GlobalTexture, desc.GlobalShape = g.mean, g.texture, g.shape
+	desc.GlobalAlphaCoverage = g.alpha
+
+	// Tuiles puis niveaux de la pyramide, à la suite du bloc global
+	rest := fixed[bs:]
+	desc.Tiles, rest = readTiles(rest, params.TilesPerRow*params.TilesPerRow, params.Bins, alpha)
+	for _, grid := range params.PyramidGrids() {
+		level := PyramidLevel{Grid: grid}
+		level.Tiles, rest = readTiles(rest, grid*grid, params.Bins, alpha)
+		desc.Levels = append(desc.Levels, level)
+	}
+	return desc, nil
+}
+
+// readTiles décode n blocs de tuiles et retourne les octets restants
+func readTiles(b []byte, n, bins int, alpha bool) ([]TileDescriptor, []byte) {
+	bs := blockSize(bins, alpha)
+	tiles := make([]TileDescriptor, n)
+	for i := range tiles {
+		t := readBlock(b[i*bs:(i+1)*bs], bins, alpha)
+		tiles[i] = TileDescriptor{
+			HistogramRGB:     t.rgb,
+			HistogramHSV:     t.hsv,
+			PHash:            t.phash,
+			MeanColor:        t.mean,
+			TextureSignature: t.texture,
+			ShapeSignature:   t.shape,
+			AlphaCoverage:    t.alpha,
+		}
+	}
+	return tiles, b[n*bs:]
+}
+
+// block : contenu décodé d'un bloc à largeur fixe
+type block struct {
+	rgb, hsv              map[string][]int
+	phash                 PHash
+	mean                  [3]float64
+	texture, shape, alpha float64
+}
+
+// readBlock décode un bloc (l'inverse de appendBlock)
+func readBlock(b []byte, bins int, alpha bool) block {
+	var blk block
+	pos := 0
+
+	readHist := func(keys [3]string) map[string][]int {
+		hist := make(map[string][]int, 3)
+		for _, k := range keys {
+			values := make([]int, bins)
+			for i := range values {
+				values[i] = int(binary.LittleEndian.Uint32(b[pos:]))
+				pos += 4
+			}
+			hist[k] = values
+		}
+		return hist
+	}
+	blk.rgb = readHist(rgbKeys)
+	blk.hsv = readHist(hsvKeys)
+
+	blk.phash = PHash(binary.LittleEndian.Uint64(b[pos:]))
+	pos += 8
+
+	var f [5]float64
+	for i := range f {
+		f[i] = math.Float64frombits(binary.LittleEndian.Uint64(b[pos:]))
+		pos += 8
+	}
+	blk.mean = [3]float64{f[0], f[1], f[2]}
+	blk.texture, blk.shape = f[3], f[4]
+
+	blk.alpha = 1 // Avant la version 3 : image supposée opaque
+	if alpha {
+		blk.alpha = math.Float64frombits(binary.LittleEndian.Uint64(b[pos:]))
+	}
+	return blk
+}
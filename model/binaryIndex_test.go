@@ -0,0 +1,108 @@
+package model
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+// testDescriptor construit un descripteur vide mais cohérent avec params
+func testDescriptor(params AnalysisParams, name string) *FullImageDescriptor {
+	hist := func(keys ...string) map[string][]int {
+		h := make(map[string][]int)
+		for _, k := range keys {
+			h[k] = make([]int, params.Bins)
+		}
+		return h
+	}
+	desc := &FullImageDescriptor{
+		SchemaVersion: SchemaVersion,
+		Params:        params,
+		ImageName:     name,
+		GlobalRGB:     hist("r", "g", "b"),
+		GlobalHSV:     hist("h", "s", "v"),
+	}
+	for i := 0; i < params.TilesPerRow*params.TilesPerRow; i++ {
+		desc.Tiles = append(desc.Tiles, TileDescriptor{HistogramRGB: hist("r", "g", "b"), HistogramHSV: hist("h", "s", "v")})
+	}
+	return desc
+}
+
+// testIndex écrit un index binaire de deux descripteurs en mémoire
+func testIndex(t *testing.T) (data []byte, firstRecord int) {
+	t.Helper()
+	var buf bytes.Buffer
+	bw, err := NewBinaryIndexWriter(&buf, CurrentParams())
+	if err != nil {
+		t.Fatal(err)
+	}
+	firstRecord = buf.Len() + bw.w.Buffered()
+	for i, name := range []string{"a.png", "b.png"} {
+		desc := testDescriptor(CurrentParams(), name)
+		desc.GlobalPHash = PHash(0x0123456789abcdef + i)
+		desc.GlobalRGB["b"][0] = 7 // Dernière valeur avant le pHash
+		if err := bw.Add(desc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := bw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes(), firstRecord
+}
+
+// Une longueur de métadonnées corrompue est refusée avant toute allocation
+func TestBinaryIndexCorruptMetaLength(t *testing.T) {
+	data, first := testIndex(t)
+
+	idx, err := newBinaryIndex(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if desc, err := idx.Descriptor(1); err != nil || desc.ImageName != "b.png" {
+		t.Fatalf("index intact : %v, %v", desc, err)
+	}
+
+	binary.LittleEndian.PutUint32(data[first:], 0xFFFFFFFF)
+	idx, err = newBinaryIndex(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := idx.Descriptor(0); !errors.Is(err, ErrBinaryIndex) {
+		t.Errorf("Descriptor : %v, ErrBinaryIndex attendu", err)
+	}
+	if _, err := idx.Source(0); !errors.Is(err, ErrBinaryIndex) {
+		t.Errorf("Source : %v, ErrBinaryIndex attendu", err)
+	}
+	if _, err := idx.Summary(0); !errors.Is(err, ErrBinaryIndex) {
+		t.Errorf("Summary : %v, ErrBinaryIndex attendu", err)
+	}
+	err = ReadBinaryIndex(bytes.NewReader(data), func(*FullImageDescriptor) error { return nil })
+	if !errors.Is(err, ErrBinaryIndex) {
+		t.Errorf("ReadBinaryIndex : %v, ErrBinaryIndex attendu", err)
+	}
+}
+
+// Le résumé d'un enregistrement porte le même pHash que son descripteur complet
+func TestBinaryIndexSummary(t *testing.T) {
+	data, _ := testIndex(t)
+	idx, err := newBinaryIndex(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < idx.Len(); i++ {
+		desc, err := idx.Descriptor(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sum, err := idx.Summary(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sum.GlobalPHash != desc.GlobalPHash || sum.ImageName != desc.ImageName || sum.Params != desc.Params {
+			t.Errorf("résumé %d : %v %q %+v, %v %q %+v attendus", i,
+				sum.GlobalPHash, sum.ImageName, sum.Params, desc.GlobalPHash, desc.ImageName, desc.Params)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// descriptorsBucket : bucket bbolt contenant les descripteurs (clé → JSON compact)
+var descriptorsBucket = []byte("descriptors")
+
+/*
+===== STOCKAGE EN BASE CLÉ-VALEUR EMBARQUÉE (bbolt) =====
+
+À QUOI ÇA SERT :
+Range toute la banque dans un seul fichier de base de données, en Go pur,
+sans serveur. Chaque écriture est une transaction : un indexeur interrompu
+en pleine écriture ne laisse jamais de descripteur à moitié écrit, la base
+revient simplement à son état avant la transaction.
+
+Les descripteurs sont stockés en JSON compact (même schéma que les fichiers,
+même migration et même contrôle de compatibilité à la lecture).
+Utilisable depuis plusieurs goroutines ; un seul processus à la fois
+peut ouvrir la base (verrou de fichier).
+*/
+type BoltStore struct {
+	db   *bolt.DB
+	path string
+}
+
+/*
+===== OUVERTURE D'UNE BASE =====
+
+Crée le fichier s'il n'existe pas. Attend au plus 5 secondes le verrou
+si un autre processus utilise déjà la base.
+*/
+func OpenBoltStore(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0o644, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("ouverture de %s : %w", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(descriptorsBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &BoltStore{db: db, path: path}, nil
+}
+
+// Location identifie une clé dans les messages : <fichier de la base>#<clé>
+func (s *BoltStore) Location(key string) string {
+	return s.path + "#" + key
+}
+
+// Get charge le descripteur d'une clé
+func (s *BoltStore) Get(key string) (*FullImageDescriptor, error) {
+	var data []byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		// Copie nécessaire : la valeur n'est valable que pendant la transaction
+		if v := tx.Bucket(descriptorsBucket).Get([]byte(key)); v != nil {
+			data = bytes.Clone(v)
+		}
+		return nil
+	})
+	if err != nil {
+		// Base fermée ou illisible : le descripteur n'est pas pour autant absent
+		return nil, &DescriptorError{Path: s.Location(key), Err: err}
+	}
+
+	if data == nil {
+		return nil, &DescriptorError{Path: s.Location(key), Kind: ErrNotFound, Err: ErrNotFound}
+	}
+	return decodeDescriptor(bytes.NewReader(data), s.Location(key))
+}
+
+// Put enregistre le descripteur d'une clé dans une transaction atomique
+func (s *BoltStore) Put(key string, desc *FullImageDescriptor) error {
+	data, err := json.Marshal(desc)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(descriptorsBucket).Put([]byte(key), data)
+	})
+}
+
+// Delete supprime le descripteur d'une clé (sans erreur si absent)
+func (s *BoltStore) Delete(key string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(descriptorsBucket).Delete([]byte(key))
+	})
+}
+
+// Keys retourne toutes les clés, dans l'ordre lexical (ordre natif de bbolt)
+func (s *BoltStore) Keys() ([]string, error) {
+	var keys []string
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(descriptorsBucket).ForEach(func(k, _ []byte) error {
+			keys = append(keys, string(k))
+			return nil
+		})
+	})
+	return keys, err
+}
+
+/*
+===== PARCOURS DE LA BASE =====
+
+Les descripteurs sont décodés un par un dans une transaction de lecture :
+fn voit un instantané cohérent de la base. fn ne doit pas écrire dans la
+même base (bbolt interdit une écriture pendant une lecture de la même goroutine).
+*/
+func (s *BoltStore) Iterate(fn func(key string, desc *FullImageDescriptor) error) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(descriptorsBucket).ForEach(func(k, v []byte) error {
+			desc, err := decodeDescriptor(bytes.NewReader(v), s.Location(string(k)))
+			if err != nil {
+				return err
+			}
+			return fn(string(k), desc)
+		})
+	})
+}
+
+// Close ferme la base et libère le verrou de fichier
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,25 @@
+package model
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+// Une base fermée n'est pas une clé absente : l'erreur de bbolt remonte
+func TestBoltStoreGetClosed(t *testing.T) {
+	s, err := OpenBoltStore(filepath.Join(t.TempDir(), "descripteurs.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get("absente"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("clé absente : erreur %v, ErrNotFound attendue", err)
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get("absente"); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("base fermée : erreur %v, erreur de lecture attendue", err)
+	}
+}
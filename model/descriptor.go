@@ -2,8 +2,11 @@ package model
 
 import (
 	"encoding/json" // Pour la sérialisation JSON automatique
-	"fmt"
+	"errors"
+	"io"
+	"io/fs"
 	"os" // Pour les opérations sur fichiers
+	"path/filepath"
 )
 
 /*
@@ -22,8 +25,8 @@ type TileDescriptor struct {
 	// FORMAT : {"h": [64 bins], "s": [64 bins], "v": [64 bins]}
 	HistogramHSV map[string][]int `json:"histogram_hsv"`
 
-	// Hash perceptuel de cette tuile (signature binaire 64 bits)
-	PHash string `json:"phash"`
+	// Hash perceptuel de cette tuile (signature binaire 64 bits, hexadécimal en JSON)
+	PHash PHash `json:"phash"`
 
 	// Couleur moyenne de cette tuile
 	// FORMAT : [Rouge, Vert, Bleu] avec valeurs 0-255
@@ -34,6 +37,26 @@ type TileDescriptor struct {
 
 	// Signature de forme de cette tuile
 	ShapeSignature float64 `json:"shape_signature"`
+
+	// Part opaque de cette tuile (0 = transparente, 1 = opaque)
+	AlphaCoverage float64 `json:"alpha_coverage"`
+}
+
+/*
+===== NIVEAU DE LA PYRAMIDE SPATIALE =====
+
+À QUOI ÇA SERT :
+Grille supplémentaire Grid×Grid de tuiles, en plus du niveau global (1×1)
+et de la grille TilesPerRow×TilesPerRow (voir AnalysisParams.Pyramid).
+Les grilles grossières tolèrent un léger décalage ou recadrage, les
+grilles fines récompensent une disposition identique.
+*/
+type PyramidLevel struct {
+	// Nombre de tuiles par ligne/colonne de ce niveau
+	Grid int `json:"grid"`
+
+	// Grid×Grid tuiles, ligne par ligne
+	Tiles []TileDescriptor `json:"tiles"`
 }
 
 /*
@@ -45,10 +68,22 @@ C'est la "carte d'identité" complète d'une image pour la reconnaissance.
 */
 type FullImageDescriptor struct {
 
+	// Version du schéma JSON (voir SchemaVersion)
+	// UTILITÉ : Détecter les fichiers écrits par une version incompatible
+	SchemaVersion int `json:"schema_version"`
+
+	// Paramètres d'analyse utilisés (taille, bins, grille)
+	// UTILITÉ : Ne comparer que des descripteurs produits avec les mêmes réglages
+	Params AnalysisParams `json:"params"`
+
 	// Nom du fichier image (sans le chemin complet)
 	// UTILITÉ : Identification et affichage des résultats
 	ImageName string `json:"image_name"`
 
+	// Empreinte du fichier image analysé (taille, date, SHA-256)
+	// UTILITÉ : Détecter une image remplacée ou modifiée depuis l'analyse
+	Source *SourceInfo `json:"source,omitempty"`
+
 	// Histogramme RGB global - Distribution générale des couleurs
 	GlobalRGB map[string][]int `json:"global_rgb"`
 
@@ -56,7 +91,7 @@ type FullImageDescriptor struct {
 	GlobalHSV map[string][]int `json:"global_hsv"`
 
 	// Hash perceptuel global - Signature structurelle de l'image entière
-	GlobalPHash string `json:"global_phash"`
+	GlobalPHash PHash `json:"global_phash"`
 
 	// Couleur moyenne globale - Teinte dominante de toute l'image
 	GlobalMeanColor [3]float64 `json:"global_mean_color"`
@@ -67,7 +102,14 @@ type FullImageDescriptor struct {
 	// Signature de forme globale - Densité des contours dans l'image
 	GlobalShape float64 `json:"global_shape"`
 
+	// Couverture alpha globale - Part opaque de l'image (1 pour une photo)
+	GlobalAlphaCoverage float64 `json:"global_alpha_coverage"`
+
 	Tiles []TileDescriptor `json:"tiles"`
+
+	// Niveaux intermédiaires de la pyramide spatiale, du plus grossier au plus fin
+	// UTILITÉ : Comparaison à plusieurs échelles (vide sans params.pyramid)
+	Levels []PyramidLevel `json:"levels,omitempty"`
 }
 
 /*
@@ -77,6 +119,15 @@ type FullImageDescriptor struct {
 Sérialise un descripteur complet dans un fichier JSON pour créer un cache persistant.
 Évite de recalculer les descripteurs à chaque utilisation (gain de temps énorme).
 
+ÉCRITURE ATOMIQUE :
+1. Écriture dans un fichier temporaire du MÊME dossier (nom unique)
+2. fsync : les données sont sur le disque avant d'être publiées
+3. Renommage vers le nom final : opération atomique du système de fichiers
+Un processus interrompu ne laisse donc jamais de JSON vide ou tronqué : le
+lecteur voit soit l'ancien fichier complet, soit le nouveau. Deux indexeurs
+qui écrivent la même clé en même temps ne se mélangent pas : chacun a son
+fichier temporaire, le dernier renommage l'emporte.
+
 Paramètres :
 - desc : descripteur à sauvegarder
 - outputPath : chemin du fichier JSON de destination
@@ -86,15 +137,43 @@ Retour :
 */
 func saveDescriptor(desc *FullImageDescriptor, outputPath string) error {
 
-	// Création du fichier de sortie (écrase s'il existe)
-	file, err := os.Create(outputPath)
+	// Fichier temporaire unique à côté de la destination
+	// (le renommage n'est atomique qu'au sein d'un même système de fichiers)
+	tmp, err := os.CreateTemp(filepath.Dir(outputPath), "."+filepath.Base(outputPath)+".*.tmp")
 	if err != nil {
 		return err // Erreur : disque plein, permissions, chemin invalide, etc.
 	}
-	defer file.Close() // Fermeture garantie même en cas d'erreur
+	defer os.Remove(tmp.Name()) // Nettoyage en cas d'échec, sans effet après le renommage
+
+	if err := writeDescriptor(tmp, desc); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	// Même droits qu'un fichier créé avec os.Create (CreateTemp crée en 0600)
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	// Données physiquement écrites avant de publier le fichier
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	// Publication atomique : remplace l'éventuel ancien descripteur
+	return os.Rename(tmp.Name(), outputPath)
+}
+
+// writeDescriptor encode le descripteur en JSON indenté
+func writeDescriptor(w io.Writer, desc *FullImageDescriptor) error {
 
 	// Configuration de l'encodeur JSON pour un rendu "joli"
-	encoder := json.NewEncoder(file)
+	encoder := json.NewEncoder(w)
 	encoder.SetIndent("", "  ") // Indentation de 2 espaces pour lisibilité
 
 	// Sérialisation automatique : Go → JSON
@@ -119,30 +198,59 @@ func SaveDescriptor(desc *FullImageDescriptor, outputPath string) error {
 Désérialise un fichier JSON pour reconstruire un descripteur complet.
 Permet de récupérer instantanément les résultats d'analyses précédentes.
 
+Les descripteurs d'un ancien schéma sont migrés (voir Migrate), puis leur
+cohérence est vérifiée (voir CheckDescriptor) : un fichier dont le contenu
+ne correspond pas à ses paramètres est rejeté plutôt que de fausser les
+scores. Comparer deux descripteurs de paramètres différents reste refusé
+par le moteur de recherche.
+
 Paramètre :
 - inputPath : chemin vers le fichier JSON à charger
 
 Retour :
-- Pointeur vers descripteur reconstitué, ou nil si échec
+- Pointeur vers descripteur reconstitué
+- *DescriptorError en cas d'échec (errors.Is : ErrNotFound, ErrMalformed, ErrIncompatible)
 */
-func LoadDescriptor(inputPath string) *FullImageDescriptor {
+func LoadDescriptor(inputPath string) (*FullImageDescriptor, error) {
 
 	// Tentative d'ouverture du fichier JSON
 	file, err := os.Open(inputPath)
 	if err != nil {
-		fmt.Println("Erreur ouverture fichier JSON:", err)
-		return nil // Fichier inexistant, permissions, etc.
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, &DescriptorError{Path: inputPath, Kind: ErrNotFound, Err: err}
+		}
+		return nil, &DescriptorError{Path: inputPath, Err: err} // Permissions, etc.
 	}
 	defer file.Close()
 
+	return decodeDescriptor(file, inputPath)
+}
+
+/*
+===== DÉCODAGE D'UN DESCRIPTEUR JSON =====
+
+Partagé par tous les stockages (fichiers, base clé-valeur) : désérialise,
+migre les anciens schémas et vérifie la cohérence du contenu.
+location identifie la source dans les messages d'erreur.
+*/
+func decodeDescriptor(r io.Reader, location string) (*FullImageDescriptor, error) {
+
 	// Structure vide pour recevoir les données désérialisées
 	var desc FullImageDescriptor
 
 	// Désérialisation JSON → Go
-	if err := json.NewDecoder(file).Decode(&desc); err != nil {
-		fmt.Println("Erreur décodage JSON:", err)
-		return nil // JSON malformé, champs manquants, etc.
+	if err := json.NewDecoder(r).Decode(&desc); err != nil {
+		return nil, &DescriptorError{Path: location, Kind: ErrMalformed, Err: err} // JSON tronqué, etc.
+	}
+
+	// Mise à niveau des anciens schémas puis contrôle de cohérence
+	// (les paramètres attendus sont vérifiés par l'appelant : cache, moteur)
+	if err := Migrate(&desc); err != nil {
+		return nil, &DescriptorError{Path: location, Kind: ErrIncompatible, Err: err}
+	}
+	if err := CheckDescriptor(&desc); err != nil {
+		return nil, &DescriptorError{Path: location, Kind: ErrIncompatible, Err: err}
 	}
 
-	return &desc // Succès : descripteur reconstitué
+	return &desc, nil // Succès : descripteur reconstitué
 }
@@ -0,0 +1,48 @@
+package model
+
+import "errors"
+
+/*
+===== CATÉGORIES D'ERREURS DE CHARGEMENT =====
+
+À tester avec errors.Is sur l'erreur retournée par LoadDescriptor :
+
+	if errors.Is(err, model.ErrNotFound) { ... régénérer ... }
+*/
+var (
+	// ErrNotFound : le fichier de descripteur n'existe pas
+	ErrNotFound = errors.New("descripteur introuvable")
+
+	// ErrMalformed : le fichier existe mais n'est pas un JSON valide (corrompu, tronqué)
+	ErrMalformed = errors.New("descripteur malformé")
+
+	// ErrIncompatible : schéma ou paramètres d'analyse incompatibles
+	ErrIncompatible = errors.New("descripteur incompatible")
+)
+
+/*
+===== ERREUR SUR UN FICHIER DE DESCRIPTEUR =====
+
+Associe l'erreur d'origine au fichier concerné et à sa catégorie (Kind),
+sans perdre la cause : errors.Is / errors.As fonctionnent sur les deux.
+*/
+type DescriptorError struct {
+	Path string // Fichier concerné
+	Kind error  // ErrNotFound, ErrMalformed, ErrIncompatible, ou nil si autre
+	Err  error  // Cause d'origine
+}
+
+func (e *DescriptorError) Error() string {
+	if e.Kind == nil || errors.Is(e.Err, e.Kind) {
+		return e.Path + " : " + e.Err.Error()
+	}
+	return e.Path + " : " + e.Kind.Error() + " : " + e.Err.Error()
+}
+
+// Unwrap expose la catégorie et la cause à errors.Is / errors.As
+func (e *DescriptorError) Unwrap() []error {
+	if e.Kind == nil {
+		return []error{e.Err}
+	}
+	return []error{e.Kind, e.Err}
+}
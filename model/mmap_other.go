@@ -0,0 +1,28 @@
+//go:build !unix
+
+package model
+
+import (
+	"io"
+	"os"
+)
+
+/*
+===== OUVERTURE SANS PROJECTION MÉMOIRE =====
+
+Repli pour les systèmes sans mmap : les enregistrements sont lus
+directement dans le fichier avec ReadAt.
+*/
+func openReaderAt(path string) (io.ReaderAt, io.Closer, int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, nil, 0, err
+	}
+	return file, file, stat.Size(), nil
+}
@@ -0,0 +1,46 @@
+//go:build unix
+
+package model
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"syscall"
+)
+
+/*
+===== OUVERTURE PAR PROJECTION MÉMOIRE (UNIX) =====
+
+Le fichier est projeté en mémoire avec mmap : le système charge les pages
+à la demande et les partage entre processus, sans copie dans le tas Go.
+*/
+func openReaderAt(path string) (io.ReaderAt, io.Closer, int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	defer file.Close() // La projection reste valide après fermeture du fichier
+
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	size := stat.Size()
+	if size == 0 {
+		return bytes.NewReader(nil), io.NopCloser(nil), 0, nil
+	}
+
+	data, err := syscall.Mmap(int(file.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	return bytes.NewReader(data), munmapCloser(data), size, nil
+}
+
+// munmapCloser libère la projection mémoire à la fermeture de l'index
+type munmapCloser []byte
+
+func (m munmapCloser) Close() error {
+	return syscall.Munmap(m)
+}
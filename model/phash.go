@@ -0,0 +1,62 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+/*
+===== HASH PERCEPTUEL 64 BITS =====
+
+À QUOI ÇA SERT :
+Représente un pHash par un entier 64 bits en mémoire : la distance de Hamming
+se calcule alors directement (XOR + comptage de bits), sans reparser une
+chaîne hexadécimale à chaque comparaison.
+
+SUR LE DISQUE :
+Le JSON garde la forme hexadécimale historique sur 16 caractères
+(ex: "fef1f10f8ffafd01"), les fichiers existants restent donc lisibles.
+*/
+type PHash uint64
+
+// String retourne la forme hexadécimale sur 16 caractères
+func (h PHash) String() string {
+	return fmt.Sprintf("%016x", uint64(h))
+}
+
+// MarshalJSON écrit le hash sous forme de chaîne hexadécimale
+func (h PHash) MarshalJSON() ([]byte, error) {
+	return json.Marshal(h.String())
+}
+
+/*
+===== LECTURE DEPUIS JSON =====
+
+Refuse les chaînes invalides (au lieu de les traiter silencieusement
+comme 0) : le descripteur est alors signalé comme malformé.
+*/
+func (h *PHash) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("pHash : %w", err)
+	}
+	v, err := ParsePHash(s)
+	if err != nil {
+		return err
+	}
+	*h = v
+	return nil
+}
+
+// ParsePHash convertit une chaîne hexadécimale (1 à 16 caractères) en PHash
+func ParsePHash(s string) (PHash, error) {
+	if s == "" || len(s) > 16 {
+		return 0, fmt.Errorf("pHash %q invalide : 1 à 16 caractères hexadécimaux attendus", s)
+	}
+	v, err := strconv.ParseUint(s, 16, 64)
+	if err != nil {
+		return 0, fmt.Errorf("pHash %q invalide : %w", s, err)
+	}
+	return PHash(v), nil
+}
@@ -0,0 +1,535 @@
+package model
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/MrIsmail1/Golang_images_matcher/config"
+)
+
+/*
+===== VERSION DU FORMAT DES DESCRIPTEURS =====
+
+À QUOI ÇA SERT :
+Numéro du schéma JSON écrit par cette version du programme.
+À incrémenter à chaque changement incompatible de FullImageDescriptor.
+
+HISTORIQUE :
+- 0 : format d'origine, sans version ni paramètres d'analyse
+- 1 : ajout de schema_version et params
+- 2 : ajout du mode de normalisation (params.normalize)
+- 3 : transparence (params.alpha, params.background) et couverture alpha
+- 4 : découpage des tuiles (params.tiling, params.tile_stride)
+- 5 : pyramide spatiale (params.pyramid, levels)
+*/
+const SchemaVersion = 5
+
+/*
+===== MODE DE NORMALISATION =====
+
+À QUOI ÇA SERT :
+Comment l'image d'origine est ramenée à la taille standard avant analyse.
+Étirer un panorama ou un portrait en carré le déforme : ses tuiles ne
+tombent plus sur le même contenu qu'une version recadrée de la même scène.
+
+MODES :
+- stretch : étire l'image en carré StandardSize×StandardSize (historique)
+- pad     : proportions conservées, bandes transparentes autour (voir AlphaMode)
+- crop    : proportions conservées, carré central de l'image
+- fit     : proportions conservées, plus grand côté à StandardSize, sans bandes
+*/
+type NormalizeMode string
+
+const (
+	NormalizeStretch NormalizeMode = "stretch"
+	NormalizePad     NormalizeMode = "pad"
+	NormalizeCrop    NormalizeMode = "crop"
+	NormalizeFit     NormalizeMode = "fit"
+)
+
+// NormalizeModes liste les modes de normalisation disponibles
+var NormalizeModes = []NormalizeMode{NormalizeStretch, NormalizePad, NormalizeCrop, NormalizeFit}
+
+// Valid indique si le mode est connu
+func (m NormalizeMode) Valid() bool {
+	for _, known := range NormalizeModes {
+		if m == known {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+===== TRAITEMENT DE LA TRANSPARENCE =====
+
+À QUOI ÇA SERT :
+Choisit comment les pixels transparents (PNG, GIF) entrent dans l'analyse.
+Par défaut, un pixel transparent compte comme un pixel noir : le fond
+transparent d'un logo domine alors ses histogrammes et sa couleur moyenne.
+
+MODES :
+- black      : pixels transparents comptés en noir (historique)
+- ignore     : pixels totalement transparents exclus des couleurs
+- background : image posée sur la couleur de fond params.background
+
+La texture, la forme et le pHash voient dans tous les cas l'image
+après ce traitement (transparence en noir en modes black et ignore).
+*/
+type AlphaMode string
+
+const (
+	AlphaBlack      AlphaMode = "black"
+	AlphaIgnore     AlphaMode = "ignore"
+	AlphaBackground AlphaMode = "background"
+)
+
+// AlphaModes liste les traitements de la transparence disponibles
+var AlphaModes = []AlphaMode{AlphaBlack, AlphaIgnore, AlphaBackground}
+
+// Valid indique si le mode est connu
+func (m AlphaMode) Valid() bool {
+	for _, known := range AlphaModes {
+		if m == known {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+===== DÉCOUPAGE EN TUILES =====
+
+À QUOI ÇA SERT :
+Choisit comment la grille TilesPerRow×TilesPerRow est posée sur l'image.
+
+MODES :
+- truncate : côté entier StandardSize/TilesPerRow, bords ignorés (historique)
+- cover    : côtés inégaux, toute l'image couverte (chevauchement si TileStride < 1)
+
+EXEMPLE (256 pixels, grille 9×9) : en truncate, tuiles de 28 pixels et
+les 4 dernières lignes et colonnes de pixels ne sont dans aucune tuile ;
+en cover, tuiles de 28 ou 29 pixels de côté.
+*/
+type TilingMode string
+
+const (
+	TilingTruncate TilingMode = "truncate"
+	TilingCover    TilingMode = "cover"
+)
+
+// TilingModes liste les découpages disponibles
+var TilingModes = []TilingMode{TilingTruncate, TilingCover}
+
+// Valid indique si le mode est connu
+func (m TilingMode) Valid() bool {
+	for _, known := range TilingModes {
+		if m == known {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+===== COULEUR DE FOND =====
+
+Lit une couleur hexadécimale "#rrggbb" (le # est facultatif).
+*/
+func ParseBackground(s string) (color.NRGBA, error) {
+	hex := strings.TrimPrefix(s, "#")
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if len(hex) != 6 || err != nil {
+		return color.NRGBA{}, fmt.Errorf("couleur de fond %q invalide (attendu : #rrggbb)", s)
+	}
+	return color.NRGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 0xff}, nil
+}
+
+// FormatBackground écrit une couleur de fond sous sa forme canonique "#rrggbb"
+func FormatBackground(c color.NRGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+/*
+===== NIVEAUX DE LA PYRAMIDE SPATIALE =====
+
+Lit une liste de grilles "2,4" (espaces tolérés) ; vide : pas de pyramide.
+*/
+func ParsePyramid(s string) ([]int, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	var grids []int
+	for _, field := range strings.Split(s, ",") {
+		g, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil {
+			return nil, fmt.Errorf("pyramide %q invalide (attendu : grilles séparées par des virgules, ex. 2,4)", s)
+		}
+		grids = append(grids, g)
+	}
+	return grids, nil
+}
+
+// FormatPyramid écrit une liste de grilles sous sa forme canonique "2,4"
+func FormatPyramid(grids []int) string {
+	fields := make([]string, len(grids))
+	for i, g := range grids {
+		fields[i] = strconv.Itoa(g)
+	}
+	return strings.Join(fields, ",")
+}
+
+/*
+===== PARAMÈTRES D'ANALYSE =====
+
+À QUOI ÇA SERT :
+Enregistre dans chaque descripteur les réglages utilisés pour le produire.
+Deux descripteurs ne sont comparables que s'ils ont les MÊMES paramètres :
+un histogramme de 32 bins ne se compare pas à un de 64 bins, une grille
+8×8 ne se compare pas tuile à tuile à une grille 9×9.
+*/
+type AnalysisParams struct {
+	// Taille standard de redimensionnement (côté en pixels)
+	StandardSize int `json:"standard_size"`
+
+	// Nombre d'intervalles des histogrammes de couleur
+	Bins int `json:"bins"`
+
+	// Nombre de tuiles par ligne/colonne de la grille locale
+	TilesPerRow int `json:"tiles_per_row"`
+
+	// Mode de ramenée à la taille standard (voir NormalizeMode)
+	Normalize NormalizeMode `json:"normalize"`
+
+	// Traitement des pixels transparents (voir AlphaMode)
+	Alpha AlphaMode `json:"alpha"`
+
+	// Couleur de fond "#rrggbb", uniquement en mode AlphaBackground
+	Background string `json:"background,omitempty"`
+
+	// Découpage de la grille de tuiles (voir TilingMode)
+	Tiling TilingMode `json:"tiling"`
+
+	// Pas entre deux tuiles voisines, en fraction du côté d'une tuile, dans ]0, 1].
+	// 1 : tuiles juxtaposées ; 0.5 : chaque tuile recouvre la moitié de sa voisine.
+	// Les tuiles restent TilesPerRow×TilesPerRow et couvrent toujours toute l'image :
+	// plus le pas est petit, plus elles sont grandes. Inférieur à 1 en mode cover seulement.
+	TileStride float64 `json:"tile_stride"`
+
+	// Grilles des niveaux intermédiaires de la pyramide spatiale, forme canonique "2,4".
+	// Chaque niveau g ajoute g×g tuiles au descripteur (voir PyramidLevel), découpées
+	// comme la grille de tuiles. Vide : seuls les niveaux global et TilesPerRow×TilesPerRow.
+	// Chaîne plutôt que liste : les paramètres restent comparables avec ==.
+	Pyramid string `json:"pyramid,omitempty"`
+}
+
+// PyramidGrids retourne les grilles de la pyramide (nil si vide ou invalide)
+func (p AnalysisParams) PyramidGrids() []int {
+	grids, err := ParsePyramid(p.Pyramid)
+	if err != nil {
+		return nil
+	}
+	return grids
+}
+
+// MaxGrid retourne la grille la plus fine, pyramide comprise
+func (p AnalysisParams) MaxGrid() int {
+	grid := p.TilesPerRow
+	for _, g := range p.PyramidGrids() {
+		grid = max(grid, g)
+	}
+	return grid
+}
+
+// CurrentParams retourne les paramètres d'analyse de la configuration actuelle
+func CurrentParams() AnalysisParams {
+	return AnalysisParams{
+		StandardSize: config.StandardSize,
+		Bins:         config.Bins,
+		TilesPerRow:  config.TilesPerRow,
+		Normalize:    NormalizeMode(config.Normalize),
+		Alpha:        AlphaMode(config.Alpha),
+		Tiling:       TilingMode(config.Tiling),
+		TileStride:   1,
+	}
+}
+
+/*
+===== PARAMÈTRES D'UNE VERSION ANTÉRIEURE =====
+
+Complète les champs absents des paramètres écrits par une version
+antérieure (descripteurs, index) avec leur valeur historique : images
+étirées, transparence comptée en noir, tuiles tronquées et juxtaposées.
+*/
+func UpgradeParams(p AnalysisParams) AnalysisParams {
+	if p.Normalize == "" {
+		p.Normalize = NormalizeStretch
+	}
+	if p.Alpha == "" {
+		p.Alpha = AlphaBlack
+	}
+	if p.Tiling == "" {
+		p.Tiling = TilingTruncate
+	}
+	if p.TileStride == 0 {
+		p.TileStride = 1
+	}
+	return p
+}
+
+/*
+===== VALIDATION DES PARAMÈTRES =====
+
+Refuse les paramètres inutilisables : tailles nulles ou négatives, mode
+de normalisation inconnu, tuiles de moins de 3 pixels de côté (la texture
+compare chaque pixel à ses voisins), couleur de fond absente ou superflue,
+pas de tuiles hors de ]0, 1], chevauchement sans le mode cover, ou
+pyramide aux niveaux non croissants ou redondants.
+*/
+func (p AnalysisParams) Validate() error {
+	switch {
+	case p.Bins <= 0 || p.TilesPerRow <= 0:
+		return fmt.Errorf("paramètres %+v invalides", p)
+	case p.StandardSize < 3*p.TilesPerRow:
+		return fmt.Errorf("taille standard %d trop petite pour une grille %d×%d", p.StandardSize, p.TilesPerRow, p.TilesPerRow)
+	case !p.Normalize.Valid():
+		return fmt.Errorf("mode de normalisation %q inconnu (disponibles : %v)", p.Normalize, NormalizeModes)
+	case !p.Alpha.Valid():
+		return fmt.Errorf("traitement de la transparence %q inconnu (disponibles : %v)", p.Alpha, AlphaModes)
+	case !p.Tiling.Valid():
+		return fmt.Errorf("découpage en tuiles %q inconnu (disponibles : %v)", p.Tiling, TilingModes)
+	case !(p.TileStride > 0 && p.TileStride <= 1):
+		return fmt.Errorf("pas de tuiles %g hors de ]0, 1]", p.TileStride)
+	case p.TileStride != 1 && p.Tiling != TilingCover:
+		return fmt.Errorf("tuiles chevauchantes (pas %g) sans le découpage %s", p.TileStride, TilingCover)
+	case p.Alpha != AlphaBackground && p.Background != "":
+		return fmt.Errorf("couleur de fond %q sans le mode %s", p.Background, AlphaBackground)
+	case p.Alpha == AlphaBackground:
+		// Forme canonique imposée : deux écritures de la même couleur
+		// rendraient des paramètres différents, donc non comparables
+		c, err := ParseBackground(p.Background)
+		if err != nil {
+			return err
+		}
+		if canon := FormatBackground(c); p.Background != canon {
+			return fmt.Errorf("couleur de fond %q à écrire %q", p.Background, canon)
+		}
+	}
+	return p.validatePyramid()
+}
+
+// validatePyramid : grilles croissantes, distinctes des niveaux global et fin, tuiles d'au moins 3 pixels
+func (p AnalysisParams) validatePyramid() error {
+	grids, err := ParsePyramid(p.Pyramid)
+	if err != nil {
+		return err
+	}
+	if canon := FormatPyramid(grids); p.Pyramid != canon {
+		return fmt.Errorf("pyramide %q à écrire %q", p.Pyramid, canon)
+	}
+	for i, g := range grids {
+		switch {
+		case g < 2:
+			return fmt.Errorf("niveau %d×%d de pyramide invalide (le niveau 1×1 est le niveau global)", g, g)
+		case g == p.TilesPerRow:
+			return fmt.Errorf("niveau %d×%d de pyramide déjà couvert par la grille de tuiles", g, g)
+		case i > 0 && g <= grids[i-1]:
+			return fmt.Errorf("niveaux de pyramide %q non strictement croissants", p.Pyramid)
+		case p.StandardSize < 3*g:
+			return fmt.Errorf("taille standard %d trop petite pour une grille %d×%d", p.StandardSize, g, g)
+		}
+	}
+	return nil
+}
+
+/*
+===== ERREUR DE DESCRIPTEUR INCOMPATIBLE =====
+
+Retournée quand un descripteur a été produit avec une autre version du schéma
+ou d'autres paramètres d'analyse : le comparer donnerait des scores faux
+(ou un index hors limites). Il faut le régénérer (sous-commande index -force).
+*/
+type IncompatibleError struct {
+	Reason string
+}
+
+func (e *IncompatibleError) Error() string {
+	return ErrIncompatible.Error() + " : " + e.Reason
+}
+
+// Is rattache l'erreur à la catégorie ErrIncompatible
+func (e *IncompatibleError) Is(target error) bool {
+	return target == ErrIncompatible
+}
+
+/*
+===== MIGRATION VERS LE SCHÉMA COURANT =====
+
+À QUOI ÇA SERT :
+Met à niveau en mémoire un descripteur écrit par une version antérieure.
+
+VERSION 0 → 1 :
+Les anciens fichiers n'enregistrent pas leurs paramètres : on les déduit du
+contenu (taille des histogrammes, nombre de tuiles). La taille standard
+n'est pas déductible, on suppose celle de la configuration courante.
+
+VERSION 1 → 2 :
+Les images étaient toujours étirées en carré : mode stretch.
+
+VERSION 2 → 3 :
+La transparence était comptée en noir (mode black). La couverture alpha
+n'était pas mesurée : les images sont supposées opaques (couverture 1).
+
+VERSION 3 → 4 :
+Les tuiles étaient tronquées et juxtaposées (découpage truncate, pas 1).
+
+VERSION 4 → 5 :
+Pas de pyramide spatiale : params.pyramid vide, aucun niveau à ajouter.
+
+Retour :
+- nil si le descripteur est au schéma courant après migration
+- *IncompatibleError si le schéma est inconnu (version plus récente)
+*/
+func Migrate(desc *FullImageDescriptor) error {
+	switch {
+	case desc.SchemaVersion == SchemaVersion:
+		return nil
+	case desc.SchemaVersion > SchemaVersion:
+		return &IncompatibleError{Reason: fmt.Sprintf(
+			"schéma v%d plus récent que celui supporté (v%d)", desc.SchemaVersion, SchemaVersion)}
+	case desc.SchemaVersion < 0:
+		return &IncompatibleError{Reason: fmt.Sprintf("schéma v%d invalide", desc.SchemaVersion)}
+	}
+
+	from := desc.SchemaVersion
+
+	// Version 0 : déduction des paramètres depuis le contenu
+	if from == 0 {
+		grid := int(math.Round(math.Sqrt(float64(len(desc.Tiles)))))
+		desc.Params = AnalysisParams{
+			StandardSize: config.StandardSize,
+			Bins:         len(desc.GlobalRGB["r"]),
+			TilesPerRow:  grid,
+		}
+	}
+
+	// Champs de paramètres absents à leur valeur historique
+	desc.Params = UpgradeParams(desc.Params)
+
+	// Versions 0 à 2 : couverture alpha non mesurée
+	if from < 3 {
+		desc.GlobalAlphaCoverage = 1
+		for i := range desc.Tiles {
+			desc.Tiles[i].AlphaCoverage = 1
+		}
+	}
+	desc.SchemaVersion = SchemaVersion
+	return nil
+}
+
+/*
+===== VÉRIFICATION DE COMPATIBILITÉ =====
+
+À QUOI ÇA SERT :
+Vérifie qu'un descripteur peut être comparé avec ceux produits par la
+configuration courante : mêmes paramètres, et contenu cohérent avec eux
+(bonne taille d'histogrammes, bon nombre de tuiles). Évite les scores
+absurdes et les paniques dans CompareDescriptors.
+*/
+func CheckCompatibility(desc *FullImageDescriptor) error {
+	return CheckCompatibilityWith(desc, CurrentParams())
+}
+
+// CheckCompatibilityWith vérifie la compatibilité avec des paramètres d'analyse choisis
+func CheckCompatibilityWith(desc *FullImageDescriptor, want AnalysisParams) error {
+	if err := CheckDescriptor(desc); err != nil {
+		return err
+	}
+	if desc.Params != want {
+		return &IncompatibleError{Reason: fmt.Sprintf(
+			"paramètres %+v, %+v attendus", desc.Params, want)}
+	}
+	return nil
+}
+
+/*
+===== VÉRIFICATION D'UN DESCRIPTEUR ISOLÉ =====
+
+Schéma courant, paramètres valides et contenu cohérent avec eux, quels
+que soient ces paramètres : un stockage peut contenir des descripteurs
+produits avec un autre mode de normalisation que celui de la
+configuration courante. La comparaison entre deux descripteurs reste
+réservée à ceux de paramètres identiques.
+*/
+func CheckDescriptor(desc *FullImageDescriptor) error {
+	if desc.SchemaVersion != SchemaVersion {
+		return &IncompatibleError{Reason: fmt.Sprintf(
+			"schéma v%d, v%d attendu", desc.SchemaVersion, SchemaVersion)}
+	}
+	if err := desc.Params.Validate(); err != nil {
+		return &IncompatibleError{Reason: err.Error()}
+	}
+	return checkShape(desc)
+}
+
+// checkShape vérifie que le contenu correspond aux paramètres annoncés
+func checkShape(desc *FullImageDescriptor) error {
+	p := desc.Params
+
+	if n := p.TilesPerRow * p.TilesPerRow; len(desc.Tiles) != n {
+		return &IncompatibleError{Reason: fmt.Sprintf("%d tuiles, %d attendues", len(desc.Tiles), n)}
+	}
+
+	grids := p.PyramidGrids()
+	if len(desc.Levels) != len(grids) {
+		return &IncompatibleError{Reason: fmt.Sprintf("%d niveaux de pyramide, %d attendus", len(desc.Levels), len(grids))}
+	}
+	for i, level := range desc.Levels {
+		if level.Grid != grids[i] {
+			return &IncompatibleError{Reason: fmt.Sprintf("niveau %d de grille %d, %d attendue", i, level.Grid, grids[i])}
+		}
+		if n := level.Grid * level.Grid; len(level.Tiles) != n {
+			return &IncompatibleError{Reason: fmt.Sprintf("niveau %d×%d : %d tuiles, %d attendues", level.Grid, level.Grid, len(level.Tiles), n)}
+		}
+	}
+
+	check := func(where string, hist map[string][]int, keys ...string) error {
+		for _, k := range keys {
+			if len(hist[k]) != p.Bins {
+				return &IncompatibleError{Reason: fmt.Sprintf(
+					"histogramme %s[%s] de %d bins, %d attendus", where, k, len(hist[k]), p.Bins)}
+			}
+		}
+		return nil
+	}
+
+	if err := check("global_rgb", desc.GlobalRGB, "r", "g", "b"); err != nil {
+		return err
+	}
+	if err := check("global_hsv", desc.GlobalHSV, "h", "s", "v"); err != nil {
+		return err
+	}
+	checkTiles := func(where string, tiles []TileDescriptor) error {
+		for i, t := range tiles {
+			if err := check(fmt.Sprintf("%s[%d].rgb", where, i), t.HistogramRGB, "r", "g", "b"); err != nil {
+				return err
+			}
+			if err := check(fmt.Sprintf("%s[%d].hsv", where, i), t.HistogramHSV, "h", "s", "v"); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := checkTiles("tiles", desc.Tiles); err != nil {
+		return err
+	}
+	for i, level := range desc.Levels {
+		if err := checkTiles(fmt.Sprintf("levels[%d].tiles", i), level.Tiles); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"time"
+)
+
+/*
+===== EMPREINTE DU FICHIER IMAGE SOURCE =====
+
+À QUOI ÇA SERT :
+Enregistre dans le descripteur l'état du fichier image au moment de l'analyse.
+Permet de savoir si le cache est encore valable : si l'image a été remplacée
+ou modifiée depuis, le descripteur doit être recalculé.
+
+STRATÉGIE EN DEUX TEMPS :
+- Taille + date de modification : vérification quasi gratuite (un simple stat)
+- SHA-256 du contenu : arbitre quand seule la date a changé (copie, restauration...)
+*/
+type SourceInfo struct {
+	// Chemin de l'image d'origine : relatif à la banque (séparateurs "/"),
+	// ou absolu pour une image hors banque
+	Path string `json:"path,omitempty"`
+
+	// Taille du fichier en octets
+	Size int64 `json:"size"`
+
+	// Date de dernière modification du fichier
+	ModTime time.Time `json:"mod_time"`
+
+	// Hash SHA-256 du contenu, en hexadécimal
+	SHA256 string `json:"sha256"`
+}
+
+/*
+===== CALCUL DE L'EMPREINTE D'UN FICHIER =====
+
+Lit le fichier une fois en entier pour calculer son SHA-256.
+*/
+func ComputeSourceInfo(path string) (SourceInfo, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return SourceInfo{}, err
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return SourceInfo{}, err
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return SourceInfo{}, err
+	}
+
+	return SourceInfo{
+		Size:    stat.Size(),
+		ModTime: stat.ModTime().UTC(),
+		SHA256:  hex.EncodeToString(h.Sum(nil)),
+	}, nil
+}
+
+/*
+===== LE DESCRIPTEUR CORRESPOND-IL ENCORE À L'IMAGE ? =====
+
+À QUOI ÇA SERT :
+Détermine si le descripteur a bien été calculé à partir du contenu ACTUEL
+du fichier image. Les descripteurs sans empreinte (anciens fichiers) sont
+considérés comme périmés.
+
+ÉTAPES :
+1. Taille différente → périmé (sans lire le fichier)
+2. Même taille et même date → à jour (sans lire le fichier)
+3. Même taille, date différente → comparaison du SHA-256
+
+Retour :
+- true si le descripteur est à jour
+- Erreur si le fichier image est illisible
+*/
+func (d *FullImageDescriptor) MatchesSource(imagePath string) (bool, error) {
+	fresh, _, err := d.checkSource(imagePath)
+	return fresh, err
+}
+
+/*
+===== MISE À JOUR DE LA DATE DE L'IMAGE =====
+
+Comme MatchesSource. Si seul le SHA-256 a permis de conclure (même contenu
+sous une autre date : copie, restauration...), la date enregistrée dans le
+descripteur est remplacée par la date actuelle du fichier et touched vaut
+true : le descripteur est à réenregistrer, le prochain contrôle se passera
+de la relecture de l'image.
+*/
+func (d *FullImageDescriptor) RefreshSource(imagePath string) (fresh, touched bool, err error) {
+	fresh, modTime, err := d.checkSource(imagePath)
+	if !fresh || modTime.IsZero() {
+		return fresh, false, err
+	}
+	d.Source.ModTime = modTime
+	return true, true, nil
+}
+
+// checkSource : étapes de MatchesSource, avec la date actuelle du fichier si le SHA-256 a tranché
+func (d *FullImageDescriptor) checkSource(imagePath string) (bool, time.Time, error) {
+	if d.Source == nil || d.Source.SHA256 == "" {
+		return false, time.Time{}, nil
+	}
+
+	stat, err := os.Stat(imagePath)
+	if err != nil {
+		return false, time.Time{}, err
+	}
+
+	if stat.Size() != d.Source.Size {
+		return false, time.Time{}, nil
+	}
+	if stat.ModTime().Equal(d.Source.ModTime) {
+		return true, time.Time{}, nil
+	}
+
+	current, err := ComputeSourceInfo(imagePath)
+	if err != nil {
+		return false, time.Time{}, err
+	}
+	if current.SHA256 != d.Source.SHA256 {
+		return false, time.Time{}, nil
+	}
+	return true, current.ModTime, nil
+}
@@ -0,0 +1,136 @@
+package model
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+/*
+===== STOCKAGE DE DESCRIPTEURS =====
+
+À QUOI ÇA SERT :
+Abstrait l'endroit où sont rangés les descripteurs, chacun identifié par une
+clé (chemin relatif de l'image dans la banque, séparateurs "/").
+Le moteur de recherche et l'indexeur ne dépendent que de cette interface.
+
+IMPLÉMENTATIONS :
+- JSONDirStore : un fichier JSON par image dans un dossier (comportement historique)
+- BoltStore    : base clé-valeur embarquée (bbolt), un seul fichier, écritures transactionnelles
+
+CONTRAT :
+- Get retourne une erreur ErrNotFound (errors.Is) si la clé est absente
+- Les erreurs de lecture sont des *DescriptorError (ErrMalformed, ErrIncompatible...)
+- Iterate s'arrête à la première erreur (de lecture ou retournée par fn)
+- Location décrit où se trouve une clé (chemin de fichier...), pour les messages
+*/
+type DescriptorStore interface {
+	Get(key string) (*FullImageDescriptor, error)
+	Put(key string, desc *FullImageDescriptor) error
+	Delete(key string) error
+	Keys() ([]string, error)
+	Iterate(fn func(key string, desc *FullImageDescriptor) error) error
+	Location(key string) string
+	Close() error
+}
+
+/*
+===== STOCKAGE EN DOSSIER DE FICHIERS JSON =====
+
+Clé "chiens/chien.png" → fichier <Dir>/chiens/chien.png.json
+*/
+type JSONDirStore struct {
+	Dir string
+}
+
+// NewJSONDirStore retourne un stockage sur le dossier dir (créé à la première écriture)
+func NewJSONDirStore(dir string) *JSONDirStore {
+	return &JSONDirStore{Dir: dir}
+}
+
+// Path retourne le fichier JSON correspondant à une clé
+func (s *JSONDirStore) Path(key string) string {
+	return filepath.Join(s.Dir, filepath.FromSlash(key)+".json")
+}
+
+// Location retourne le fichier JSON d'une clé
+func (s *JSONDirStore) Location(key string) string {
+	return s.Path(key)
+}
+
+// Get charge le descripteur d'une clé
+func (s *JSONDirStore) Get(key string) (*FullImageDescriptor, error) {
+	return LoadDescriptor(s.Path(key))
+}
+
+// Put enregistre le descripteur d'une clé (sous-dossiers créés si besoin, écriture atomique)
+func (s *JSONDirStore) Put(key string, desc *FullImageDescriptor) error {
+	path := s.Path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return SaveDescriptor(desc, path)
+}
+
+// Delete supprime le descripteur d'une clé (sans erreur si absent)
+func (s *JSONDirStore) Delete(key string) error {
+	err := os.Remove(s.Path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+/*
+===== CLÉS DU DOSSIER =====
+
+Tous les fichiers *.json du dossier et de ses sous-dossiers, dans l'ordre
+lexical des chemins. Un dossier inexistant donne une liste vide.
+*/
+func (s *JSONDirStore) Keys() ([]string, error) {
+	var keys []string
+
+	err := filepath.WalkDir(s.Dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.EqualFold(filepath.Ext(path), ".json") {
+			return nil
+		}
+		rel, err := filepath.Rel(s.Dir, path)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, filepath.ToSlash(rel[:len(rel)-len(".json")]))
+		return nil
+	})
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// Iterate charge et passe à fn chaque descripteur du dossier
+func (s *JSONDirStore) Iterate(fn func(key string, desc *FullImageDescriptor) error) error {
+	keys, err := s.Keys()
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		desc, err := s.Get(key)
+		if err != nil {
+			return err
+		}
+		if err := fn(key, desc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close n'a rien à libérer pour un dossier
+func (s *JSONDirStore) Close() error {
+	return nil
+}
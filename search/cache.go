@@ -0,0 +1,187 @@
+package search
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/MrIsmail1/Golang_images_matcher/analyzer"
+	"github.com/MrIsmail1/Golang_images_matcher/model"
+)
+
+// externalDir : sous-dossier du cache réservé aux images hors de la banque
+const externalDir = "_externes"
+
+// IsExternalKey indique si une clé de cache désigne une image hors de la banque (requête)
+func IsExternalKey(key string) bool {
+	return strings.HasPrefix(key, externalDir+"/")
+}
+
+// IsExternalSource indique si une empreinte désigne une image hors de la banque
+// (chemin absolu, voir Cache.analyze) : ces descripteurs ne sont jamais des candidats
+func IsExternalSource(source *model.SourceInfo) bool {
+	return source != nil && filepath.IsAbs(source.Path)
+}
+
+/*
+===== CACHE DES DESCRIPTEURS =====
+
+À QUOI ÇA SERT :
+Associe chaque image à une clé UNIQUE dans un stockage de descripteurs
+(dossier de JSON, base clé-valeur... voir model.DescriptorStore).
+
+CLÉ DE CACHE :
+- Image de la banque : chemin relatif à la banque, extension comprise
+- Image hors banque : _externes/<hash du chemin absolu>-<nom>
+
+EXEMPLE : banque/images/chiens/chien.png → clé "chiens/chien.png"
+(fichier banque/json/chiens/chien.png.json avec un model.JSONDirStore).
+chien.png et chien.jpg, ou deux chien.png de sous-dossiers différents, ont
+ainsi chacun leur propre descripteur. Les descripteurs d'images hors banque
+ne font pas partie des candidats lors des recherches.
+
+Avec Store nil, rien n'est lu ni écrit : les images sont toujours analysées.
+Un descripteur en cache produit avec d'autres paramètres d'analyse que
+Params (autre mode de normalisation par exemple) est recalculé.
+*/
+type Cache struct {
+	BankDir string                // Dossier racine des images de la banque
+	Store   model.DescriptorStore // Stockage des descripteurs (nil = pas de cache)
+	Params  model.AnalysisParams  // Paramètres d'analyse (valeur zéro = model.CurrentParams)
+}
+
+// params retourne les paramètres d'analyse effectifs du cache
+func (c Cache) params() model.AnalysisParams {
+	if c.Params == (model.AnalysisParams{}) {
+		return model.CurrentParams()
+	}
+	return c.Params
+}
+
+/*
+===== CLÉ DE CACHE D'UNE IMAGE =====
+
+Chemin relatif à la banque (séparateurs "/"), ou clé "_externes/..." pour
+une image située hors de la banque.
+*/
+func (c Cache) Key(imagePath string) string {
+	abs, err := filepath.Abs(imagePath)
+	if err != nil {
+		abs = filepath.Clean(imagePath)
+	}
+
+	if c.BankDir != "" {
+		if root, err := filepath.Abs(c.BankDir); err == nil {
+			if rel, err := filepath.Rel(root, abs); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				return filepath.ToSlash(rel)
+			}
+		}
+	}
+
+	// Hors banque : le hash du chemin absolu distingue les homonymes
+	sum := sha256.Sum256([]byte(abs))
+	return externalDir + "/" + hex.EncodeToString(sum[:8]) + "-" + filepath.Base(abs)
+}
+
+/*
+===== DESCRIPTEUR D'UNE IMAGE (CACHE OU ANALYSE) =====
+
+À QUOI ÇA SERT :
+Retourne le descripteur d'une image en réutilisant le cache s'il est
+lisible, compatible et calculé sur le contenu actuel de l'image, sinon en analysant
+l'image puis en sauvegardant le résultat pour les prochaines fois.
+
+Retour :
+- Descripteur de l'image
+- Emplacement du descripteur dans le cache ("" sans cache)
+- Erreur si l'analyse ou la sauvegarde échoue
+*/
+func (c Cache) Describe(imagePath string) (*model.FullImageDescriptor, string, error) {
+	if c.Store == nil {
+		desc, err := c.analyze(imagePath)
+		return desc, "", err
+	}
+
+	key := c.Key(imagePath)
+
+	// Chargement ultra-rapide depuis le cache (quelques millisecondes)
+	// Cache absent, corrompu, incompatible ou périmé : on retombe sur l'analyse
+	if desc, ok := c.lookup(key, imagePath); ok {
+		return desc, c.Store.Location(key), nil
+	}
+
+	// Analyse complète de l'image (opération coûteuse)
+	desc, err := c.analyze(imagePath)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Sauvegarde du descripteur en cache pour les prochaines fois
+	if err := c.Store.Put(key, desc); err != nil {
+		return nil, "", fmt.Errorf("sauvegarde de %s : %w", c.Store.Location(key), err)
+	}
+
+	return desc, c.Store.Location(key), nil
+}
+
+/*
+===== DESCRIPTEUR EN CACHE À JOUR ? =====
+
+Le descripteur doit être lisible avec le schéma courant, produit avec les
+paramètres du cache, ET correspondre au contenu actuel de l'image
+(taille, date, SHA-256). Un descripteur reconnu à son SHA-256 sous une
+nouvelle date est réenregistré avec cette date : les appels suivants ne
+relisent plus l'image.
+*/
+func (c Cache) lookup(key, imagePath string) (*model.FullImageDescriptor, bool) {
+	desc, err := c.Store.Get(key)
+	if err != nil || desc.Params != c.params() {
+		return nil, false
+	}
+	fresh, touched, err := desc.RefreshSource(imagePath)
+	if err != nil || !fresh {
+		return desc, false
+	}
+	if touched {
+		// Échec sans gravité : le descripteur reste valable, le SHA-256 sera recalculé
+		c.Store.Put(key, desc)
+	}
+	return desc, true
+}
+
+/*
+===== ANALYSE AVEC LIEN VERS LA SOURCE =====
+
+Analyse l'image et enregistre dans le descripteur le chemin d'origine :
+relatif à la banque pour ses images, absolu pour les autres.
+*/
+func (c Cache) analyze(imagePath string) (*model.FullImageDescriptor, error) {
+	desc, err := analyzer.AnalyzeImageWith(imagePath, c.params())
+	if err != nil {
+		return nil, fmt.Errorf("analyse de %s : %w", imagePath, err)
+	}
+
+	key := c.Key(imagePath)
+	if IsExternalKey(key) {
+		if abs, err := filepath.Abs(imagePath); err == nil {
+			key = abs
+		}
+	}
+	desc.Source.Path = key
+	return desc, nil
+}
+
+/*
+===== IDENTITÉ D'UN DESCRIPTEUR =====
+
+Chemin source enregistré dans le descripteur, ou à défaut (anciens fichiers)
+le nom de l'image. Sert à l'auto-exclusion de la requête.
+*/
+func identity(desc *model.FullImageDescriptor) string {
+	if path := sourcePath(desc); path != "" {
+		return path
+	}
+	return desc.ImageName
+}
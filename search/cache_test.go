@@ -0,0 +1,51 @@
+package search
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/MrIsmail1/Golang_images_matcher/internal/testbank"
+	"github.com/MrIsmail1/Golang_images_matcher/model"
+)
+
+// Même contenu sous une nouvelle date : le descripteur en cache est gardé et prend la nouvelle date
+func TestCacheRefreshesModTime(t *testing.T) {
+	testbank.Require(t)
+	data, err := os.ReadFile(filepath.Join(testbank.Dir, "chien13.png"))
+	if err != nil {
+		t.Skipf("image de la banque absente : %v", err)
+	}
+	bank := t.TempDir()
+	imagePath := filepath.Join(bank, "chien13.png")
+	if err := os.WriteFile(imagePath, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	store := model.NewJSONDirStore(t.TempDir())
+	cache := Cache{BankDir: bank, Store: store}
+	first, _, err := cache.Describe(imagePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	touched := first.Source.ModTime.Add(-time.Hour)
+	if err := os.Chtimes(imagePath, touched, touched); err != nil {
+		t.Fatal(err)
+	}
+	if _, fresh := cache.lookup(cache.Key(imagePath), imagePath); !fresh {
+		t.Fatal("descripteur périmé alors que le contenu est identique")
+	}
+
+	stored, err := store.Get(cache.Key(imagePath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stored.Source.ModTime.Equal(touched) {
+		t.Errorf("date enregistrée %v, %v attendue", stored.Source.ModTime, touched)
+	}
+	if stored.Source.SHA256 != first.Source.SHA256 {
+		t.Errorf("SHA-256 modifié : %s, %s attendu", stored.Source.SHA256, first.Source.SHA256)
+	}
+}
@@ -0,0 +1,474 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"sort"
+	"sync"
+
+	"github.com/MrIsmail1/Golang_images_matcher/compare"
+	"github.com/MrIsmail1/Golang_images_matcher/model"
+)
+
+/*
+===== ENTRÉE DE LA BANQUE =====
+
+Une image connue du moteur : soit un descripteur déjà en mémoire,
+soit un descripteur chargé à la demande (économise la RAM pour les grosses
+banques) depuis un fichier JSON ou un enregistrement d'index binaire.
+*/
+type entry struct {
+	key  string                                     // Clé dans le stockage ("" hors stockage)
+	path string                                     // Origine du descripteur ("" si en mémoire uniquement)
+	desc *model.FullImageDescriptor                 // Descripteur en mémoire (nil si chargé à la demande)
+	load func() (*model.FullImageDescriptor, error) // Chargement à la demande
+
+	// Résumé sans histogrammes ni tuiles (voir model.BinaryIndex.Summary), ou nil
+	loadSummary func() (*model.FullImageDescriptor, error)
+}
+
+// descriptor retourne le descripteur de l'entrée, en le chargeant si besoin
+func (en entry) descriptor() (*model.FullImageDescriptor, error) {
+	if en.desc != nil {
+		return en.desc, nil
+	}
+	return en.load()
+}
+
+// summary retourne de quoi indexer le pHash de l'entrée, sans la décoder entièrement si possible
+func (en entry) summary() (*model.FullImageDescriptor, error) {
+	if en.desc == nil && en.loadSummary != nil {
+		return en.loadSummary()
+	}
+	return en.descriptor()
+}
+
+/*
+===== MOTEUR DE RECHERCHE =====
+
+À QUOI ÇA SERT :
+Regroupe toute la logique de recherche : parcours de la banque, cache des
+descripteurs, auto-exclusion et classement. Utilisable comme bibliothèque
+par n'importe quel service, main.go n'en est qu'un client.
+*/
+type Engine struct {
+	entries []entry
+	cache   Cache     // Cache des descripteurs (Dir vide = pas de cache disque)
+	closer  io.Closer // Ressource à libérer par Close (index binaire), ou nil
+
+	// Pré-filtre pHash, construit au premier usage (voir phashIndex)
+	phashOnce sync.Once
+	phash     *phashIndex
+
+	// Index vectoriel, construit (BuildVectorIndex) ou chargé (LoadVectorIndex)
+	vectorMu sync.Mutex
+	vector   *vectorIndex
+}
+
+/*
+===== OPTIONS D'UNE REQUÊTE =====
+
+La valeur zéro est utilisable : tous les résultats, requête exclue.
+*/
+type Options struct {
+	// TopK : nombre maximum de résultats (≤ 0 = toute la banque)
+	TopK int
+
+	// IncludeSelf : garde l'image de requête dans les résultats si elle est dans la banque
+	IncludeSelf bool
+
+	// Workers : nombre de goroutines de comparaison (≤ 0 = runtime.NumCPU())
+	Workers int
+
+	// PHashCandidates : si > 0, seules les N images dont le pHash global est
+	// le plus proche de la requête sont comparées (pré-filtre BK-tree)
+	PHashCandidates int
+
+	// PHashRadius : si > 0, seules les images à distance de Hamming ≤ PHashRadius
+	// de la requête (pHash global) sont comparées. Combinable avec PHashCandidates.
+	PHashRadius int
+
+	// VectorCandidates : si > 0, seules les N images les plus proches dans
+	// l'index vectoriel (HNSW) sont comparées. Exclusif avec le pré-filtre pHash.
+	VectorCandidates int
+
+	// Scoring : réglages du score (nil = compare.DefaultScoring())
+	Scoring *compare.ScoringConfig
+
+	// Explain : joint à chaque correspondance le détail de son score (Match.Breakdown)
+	Explain bool
+}
+
+/*
+===== UNE CORRESPONDANCE =====
+
+Quelle image, quel fichier de descripteur, et avec quel score (0-100 %).
+SourcePath est le chemin de l'image relatif à la banque (vide pour les
+anciens descripteurs), qui distingue deux images de même nom.
+Breakdown détaille le score, uniquement avec Options.Explain.
+*/
+type Match struct {
+	ImageName      string             `json:"image_name"`
+	SourcePath     string             `json:"source_path,omitempty"`
+	DescriptorPath string             `json:"descriptor_path,omitempty"`
+	Score          float64            `json:"score"`
+	Breakdown      *compare.Breakdown `json:"breakdown,omitempty"`
+}
+
+/*
+===== RÉSULTAT D'UNE REQUÊTE =====
+
+Matches est trié par score décroissant. Skipped liste les descripteurs
+de la banque qui n'ont pas pu être chargés ou qui sont incompatibles avec
+la requête (ignorés plutôt que bloquants).
+*/
+type Result struct {
+	Query    string    `json:"query"`
+	Matches  []Match   `json:"matches"`
+	Compared int       `json:"compared"`
+	Skipped  []Skipped `json:"skipped,omitempty"`
+}
+
+/*
+===== ENTRÉE IGNORÉE =====
+
+Un descripteur de la banque écarté de la recherche, et pourquoi.
+Err est un *model.DescriptorError pour les fichiers illisibles :
+errors.Is(s.Err, model.ErrMalformed) permet de trier les causes.
+*/
+type Skipped struct {
+	Path string `json:"path"`
+	Err  error  `json:"-"`
+}
+
+// MarshalJSON expose la cause sous forme de texte
+func (s Skipped) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Path   string `json:"path"`
+		Reason string `json:"reason"`
+	}{s.Path, s.Err.Error()})
+}
+
+// errIncompatibleQuery : descripteur de la banque produit avec d'autres réglages que la requête
+var errIncompatibleQuery = errors.New("paramètres d'analyse différents de la requête")
+
+/*
+===== MOTEUR À PARTIR DE DESCRIPTEURS EN MÉMOIRE =====
+
+Utile pour les services qui gèrent eux-mêmes le stockage.
+Sans dossier de cache, QueryImage analyse l'image à chaque appel.
+*/
+func NewEngine(descs []*model.FullImageDescriptor) *Engine {
+	e := &Engine{}
+	for _, d := range descs {
+		if d != nil {
+			e.entries = append(e.entries, entry{desc: d})
+		}
+	}
+	return e
+}
+
+/*
+===== MOTEUR À PARTIR D'UN STOCKAGE DE DESCRIPTEURS =====
+
+Référence toutes les clés du stockage (descripteurs chargés à la demande
+lors des requêtes), hors descripteurs d'images externes. Le même stockage
+sert de cache à QueryImage, avec les clés relatives à bankDir.
+Le stockage reste à la charge de l'appelant (Close du moteur ne le ferme pas).
+*/
+func NewEngineFromStore(bankDir string, store model.DescriptorStore) (*Engine, error) {
+	return NewEngineFromCache(Cache{BankDir: bankDir, Store: store})
+}
+
+/*
+===== MOTEUR À PARTIR D'UN CACHE =====
+
+Comme NewEngineFromStore, avec les paramètres d'analyse du cache (mode de
+normalisation...) pour les images de requête : seuls les descripteurs de
+la banque produits avec ces mêmes paramètres leur sont comparés.
+*/
+func NewEngineFromCache(cache Cache) (*Engine, error) {
+	store := cache.Store
+	keys, err := store.Keys()
+	if err != nil {
+		return nil, err
+	}
+
+	e := &Engine{cache: cache}
+	for _, key := range keys {
+		if IsExternalKey(key) {
+			continue // Requêtes hors banque : pas des candidats
+		}
+		e.entries = append(e.entries, entry{
+			key:  key,
+			path: store.Location(key),
+			load: func() (*model.FullImageDescriptor, error) { return store.Get(key) },
+		})
+	}
+	return e, nil
+}
+
+/*
+===== MOTEUR À PARTIR D'UN DOSSIER DE DESCRIPTEURS =====
+
+Raccourci pour NewEngineFromStore sur un model.JSONDirStore.
+*/
+func NewEngineFromDir(bankDir, cacheDir string) (*Engine, error) {
+	return NewEngineFromStore(bankDir, model.NewJSONDirStore(cacheDir))
+}
+
+/*
+===== MOTEUR À PARTIR D'UN INDEX BINAIRE =====
+
+Ouvre un index binaire (voir model.OpenBinaryIndex) : chaque enregistrement
+est décodé à la demande pendant les requêtes. cache sert uniquement à
+obtenir le descripteur des images de requête dans QueryImage.
+Les enregistrements d'images hors banque (index écrit par une version
+antérieure de convert) sont écartés. Le moteur doit être fermé avec Close.
+*/
+func NewEngineFromBinaryIndex(indexPath string, cache Cache) (*Engine, error) {
+	idx, err := model.OpenBinaryIndex(indexPath)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &Engine{cache: cache, closer: idx}
+	for i := 0; i < idx.Len(); i++ {
+		// Requêtes hors banque écrites par erreur dans l'index : pas des candidats
+		source, err := idx.Source(i)
+		if err != nil {
+			idx.Close()
+			return nil, err
+		}
+		if IsExternalSource(source) {
+			continue
+		}
+		e.entries = append(e.entries, entry{
+			path:        fmt.Sprintf("%s#%d", indexPath, i),
+			load:        func() (*model.FullImageDescriptor, error) { return idx.Descriptor(i) },
+			loadSummary: func() (*model.FullImageDescriptor, error) { return idx.Summary(i) },
+		})
+	}
+	return e, nil
+}
+
+// Close libère les ressources du moteur (index binaire ouvert)
+func (e *Engine) Close() error {
+	if e.closer == nil {
+		return nil
+	}
+	return e.closer.Close()
+}
+
+// name identifie l'entrée dans les messages (chemin, sinon nom d'image)
+func (en entry) name() string {
+	if en.path != "" {
+		return en.path
+	}
+	return en.desc.ImageName
+}
+
+/*
+===== COMPATIBILITÉ DE DEUX DESCRIPTEURS =====
+
+Deux descripteurs ne se comparent que s'ils partagent le même schéma et
+les mêmes paramètres d'analyse (taille, bins, grille, normalisation).
+*/
+func comparable(a, b *model.FullImageDescriptor) bool {
+	return a.SchemaVersion == b.SchemaVersion && a.Params == b.Params
+}
+
+// sourcePath retourne le chemin d'origine enregistré dans le descripteur
+func sourcePath(desc *model.FullImageDescriptor) string {
+	if desc.Source == nil {
+		return ""
+	}
+	return desc.Source.Path
+}
+
+// Len retourne le nombre d'images de la banque
+func (e *Engine) Len() int {
+	return len(e.entries)
+}
+
+/*
+===== REQUÊTE À PARTIR D'UN DESCRIPTEUR =====
+
+À QUOI ÇA SERT :
+Compare le descripteur à toute la banque et retourne les meilleures
+correspondances triées. Équivaut à QueryContext sans délai ni annulation.
+*/
+func (e *Engine) Query(desc *model.FullImageDescriptor, opts Options) (*Result, error) {
+	return e.QueryContext(context.Background(), desc, opts)
+}
+
+/*
+===== REQUÊTE CONCURRENTE ANNULABLE =====
+
+FONCTIONNEMENT :
+1. Les index des entrées à comparer sont distribués aux workers
+2. Chaque worker charge, compare et garde ses K meilleurs dans son propre tas
+3. Les tas des workers sont fusionnés en un tas final borné à K
+
+PRÉ-FILTRE :
+Avec Options.PHashCandidates, Options.PHashRadius ou Options.VectorCandidates,
+seules les entrées retenues par l'index pHash ou vectoriel sont comparées
+(voir candidates).
+
+ANNULATION :
+Les workers s'arrêtent dès que ctx est annulé ou que son délai expire ;
+la requête retourne alors ctx.Err() et aucun résultat partiel.
+
+ORDRE STABLE :
+À score égal, on départage par nom d'image puis par chemin du descripteur,
+pour que deux exécutions identiques donnent toujours le même classement.
+*/
+func (e *Engine) QueryContext(ctx context.Context, desc *model.FullImageDescriptor, opts Options) (*Result, error) {
+	if desc == nil {
+		return nil, fmt.Errorf("search: descripteur de requête nil")
+	}
+
+	// Entrées à comparer : toute la banque, ou les candidats du pré-filtre pHash
+	targets, preSkipped, err := e.candidates(desc, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if workers > len(targets) {
+		workers = len(targets)
+	}
+
+	scoring := compare.DefaultScoring()
+	if opts.Scoring != nil {
+		if err := opts.Scoring.Validate(); err != nil {
+			return nil, err
+		}
+		scoring = *opts.Scoring
+	}
+
+	// Résultats propres à chaque worker : aucun verrou pendant les comparaisons
+	type partial struct {
+		best     *topK
+		compared int
+		skipped  []Skipped
+	}
+	partials := make([]partial, workers)
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		partials[w].best = newTopK(opts.TopK)
+		wg.Add(1)
+		go func(p *partial) {
+			defer wg.Done()
+			for i := range jobs {
+				if ctx.Err() != nil {
+					continue // Vide le canal sans travailler
+				}
+				en := e.entries[i]
+
+				bankDesc, err := en.descriptor()
+				if err != nil {
+					p.skipped = append(p.skipped, Skipped{Path: en.path, Err: err})
+					continue
+				}
+
+				// Auto-exclusion : évite de comparer l'image avec elle-même
+				if !opts.IncludeSelf && identity(bankDesc) == identity(desc) {
+					continue
+				}
+
+				// Descripteurs produits avec d'autres réglages : non comparables
+				if !comparable(desc, bankDesc) {
+					p.skipped = append(p.skipped, Skipped{Path: en.name(), Err: &model.DescriptorError{
+						Path: en.name(), Kind: model.ErrIncompatible, Err: errIncompatibleQuery,
+					}})
+					continue
+				}
+
+				m := Match{
+					ImageName:      bankDesc.ImageName,
+					SourcePath:     sourcePath(bankDesc),
+					DescriptorPath: en.path,
+				}
+				if opts.Explain {
+					m.Breakdown = compare.ExplainDescriptors(desc, bankDesc, scoring)
+					m.Score = m.Breakdown.Score
+				} else {
+					m.Score = compare.CompareDescriptorsWith(desc, bankDesc, scoring)
+				}
+				p.compared++
+				p.best.push(m)
+			}
+		}(&partials[w])
+	}
+
+	// Distribution des entrées, interrompue dès l'annulation
+distribute:
+	for _, i := range targets {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break distribute
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// Fusion des tas des workers
+	res := &Result{Query: desc.ImageName, Skipped: preSkipped}
+	best := newTopK(opts.TopK)
+	for _, p := range partials {
+		for _, m := range p.best.items {
+			best.push(m)
+		}
+		res.Compared += p.compared
+		res.Skipped = append(res.Skipped, p.skipped...)
+	}
+	res.Matches = best.sorted()
+	sort.Slice(res.Skipped, func(i, j int) bool { return res.Skipped[i].Path < res.Skipped[j].Path })
+	return res, nil
+}
+
+/*
+===== REQUÊTE À PARTIR D'UNE IMAGE =====
+
+Obtient le descripteur de l'image (cache ou analyse) puis lance Query.
+*/
+func (e *Engine) QueryImage(imagePath string, opts Options) (*Result, error) {
+	return e.QueryImageContext(context.Background(), imagePath, opts)
+}
+
+// QueryImageContext est la variante annulable de QueryImage
+func (e *Engine) QueryImageContext(ctx context.Context, imagePath string, opts Options) (*Result, error) {
+	desc, _, err := e.cache.Describe(imagePath)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := e.QueryContext(ctx, desc, opts)
+	if err != nil {
+		return nil, err
+	}
+	res.Query = imagePath
+	return res, nil
+}
+
+// sortMatches trie par score décroissant avec départage déterministe
+func sortMatches(matches []Match) {
+	sort.Slice(matches, func(i, j int) bool {
+		return better(matches[i], matches[j])
+	})
+}
@@ -0,0 +1,68 @@
+package search
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/MrIsmail1/Golang_images_matcher/analyzer"
+	"github.com/MrIsmail1/Golang_images_matcher/internal/testbank"
+	"github.com/MrIsmail1/Golang_images_matcher/model"
+)
+
+// bankDescriptors analyse les images de la banque (toutes si n = 0, sinon les n premières)
+func bankDescriptors(t *testing.T, n int) ([]string, []*model.FullImageDescriptor) {
+	t.Helper()
+	testbank.Require(t)
+	images, err := ListImages(testbank.Dir)
+	if err != nil || len(images) < max(n, 2) {
+		t.Skipf("banque trop petite : %v", err)
+	}
+	if n > 0 {
+		images = images[:n]
+	}
+	return images, testbank.Descriptors(t, model.CurrentParams(), images...)
+}
+
+/*
+===== COPIES RETROUVÉES EN TÊTE =====
+
+Non-régression du score : une copie réencodée en JPEG et une copie réduite
+de moitié de chaque image de la banque doivent la retrouver en tête face
+à toute la banque. Un doublon exact de l'original (même score) ne compte
+pas comme un échec.
+*/
+func TestCopiesRankFirst(t *testing.T) {
+	images, descs := bankDescriptors(t, 0)
+	engine := NewEngine(descs)
+
+	tmpDir := t.TempDir()
+	variants := []struct {
+		name  string
+		scale float64
+	}{
+		{"réencodée", 1},
+		{"réduite", 0.5},
+	}
+	for i, path := range images {
+		for j, v := range variants {
+			copyPath := filepath.Join(tmpDir, fmt.Sprintf("%d-%d.jpg", i, j))
+			if err := WriteJPEGCopy(path, copyPath, v.scale, 75); err != nil {
+				t.Fatalf("%s : %v", path, err)
+			}
+			copyDesc, err := analyzer.AnalyzeImage(copyPath)
+			if err != nil {
+				t.Fatalf("%s : %v", copyPath, err)
+			}
+
+			// IncludeSelf : la copie ne doit jamais être confondue avec une image de la banque
+			res, err := engine.Query(copyDesc, Options{IncludeSelf: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rank := OriginalRank(res.Matches, descs[i]); rank != 1 {
+				t.Errorf("%s (copie %s) : rang %d/%d, 1 attendu", path, v.name, rank, len(res.Matches))
+			}
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/MrIsmail1/Golang_images_matcher/model"
+)
+
+/*
+===== STATUT D'UNE IMAGE APRÈS INDEXATION =====
+*/
+type IndexStatus int
+
+const (
+	StatusGenerated IndexStatus = iota // Descripteur (re)généré
+	StatusUpToDate                     // Descripteur déjà à jour, image ignorée
+	StatusFailed                       // Échec d'analyse ou de sauvegarde
+)
+
+// String rend le statut lisible dans les messages de progression
+func (s IndexStatus) String() string {
+	switch s {
+	case StatusGenerated:
+		return "généré"
+	case StatusUpToDate:
+		return "à jour"
+	default:
+		return "échec"
+	}
+}
+
+/*
+===== PROGRESSION DE L'INDEXATION =====
+
+Envoyée après chaque image traitée. Done compte les images terminées
+(quel que soit leur statut) sur Total.
+*/
+type IndexProgress struct {
+	Done   int
+	Total  int
+	Path   string
+	Status IndexStatus
+	Err    error
+}
+
+/*
+===== OPTIONS D'INDEXATION =====
+
+La valeur zéro est utilisable : un worker par CPU, images à jour ignorées,
+paramètres d'analyse de la configuration.
+*/
+type IndexOptions struct {
+	// Workers : nombre d'analyses simultanées (≤ 0 = runtime.NumCPU())
+	Workers int
+
+	// Force : régénère les descripteurs même s'ils sont à jour
+	Force bool
+
+	// Params : paramètres d'analyse (valeur zéro = model.CurrentParams) ;
+	// les descripteurs produits avec d'autres paramètres sont régénérés
+	Params model.AnalysisParams
+
+	// Progress : appelée après chaque image, toujours depuis la même goroutine
+	Progress func(IndexProgress)
+}
+
+// IndexError associe une erreur à l'image qui l'a provoquée
+type IndexError struct {
+	Path string
+	Err  error
+}
+
+func (e IndexError) Error() string {
+	return e.Path + " : " + e.Err.Error()
+}
+
+/*
+===== BILAN D'UNE INDEXATION =====
+
+Les erreurs sont collectées image par image : une image corrompue
+n'interrompt pas l'indexation du reste de la banque.
+*/
+type IndexReport struct {
+	Total     int
+	Generated int
+	UpToDate  int
+	Errors    []IndexError
+}
+
+// errNoStore : indexation demandée sans stockage où enregistrer les descripteurs
+var errNoStore = errors.New("search: aucun stockage de descripteurs")
+
+/*
+===== INDEXATION PARALLÈLE D'UN DOSSIER D'IMAGES =====
+
+À QUOI ÇA SERT :
+Génère le descripteur de chaque image du dossier bankDir dans le stockage store.
+AnalyzeImage est coûteux en CPU (DCT sur l'image et sur 81 tuiles), on
+répartit donc les images sur un pool de workers.
+
+FONCTIONNEMENT :
+1. Un canal "jobs" distribue les chemins d'images aux workers
+2. Chaque worker analyse et sauvegarde, puis publie son résultat
+3. La goroutine appelante agrège les résultats (bilan + progression)
+
+Retour :
+- Bilan de l'indexation (erreurs par fichier incluses)
+- Erreur uniquement si store est nil ou si le dossier lui-même est illisible
+*/
+func IndexDirectory(bankDir string, store model.DescriptorStore, opts IndexOptions) (*IndexReport, error) {
+	if store == nil {
+		return nil, errNoStore // Sinon panique dans les workers au premier accès au stockage
+	}
+	images, err := ListImages(bankDir)
+	if err != nil {
+		return nil, err
+	}
+	cache := Cache{BankDir: bankDir, Store: store, Params: opts.Params}
+
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	jobs := make(chan string)
+	results := make(chan IndexProgress)
+
+	// Producteur : distribue les images aux workers
+	go func() {
+		defer close(jobs)
+		for _, img := range images {
+			jobs <- img
+		}
+	}()
+
+	// Pool de workers
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for imagePath := range jobs {
+				status, err := indexImage(cache, imagePath, opts.Force)
+				results <- IndexProgress{Path: imagePath, Status: status, Err: err}
+			}
+		}()
+	}
+
+	// Fermeture du canal de résultats une fois tous les workers terminés
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// Agrégation dans la goroutine appelante : pas besoin de verrou
+	report := &IndexReport{Total: len(images)}
+	for p := range results {
+		switch p.Status {
+		case StatusGenerated:
+			report.Generated++
+		case StatusUpToDate:
+			report.UpToDate++
+		default:
+			report.Errors = append(report.Errors, IndexError{Path: p.Path, Err: p.Err})
+		}
+
+		if opts.Progress != nil {
+			p.Done = report.Generated + report.UpToDate + len(report.Errors)
+			p.Total = report.Total
+			opts.Progress(p)
+		}
+	}
+
+	return report, nil
+}
+
+// indexImage analyse une image et sauvegarde son descripteur, sauf s'il est à jour
+func indexImage(cache Cache, imagePath string, force bool) (IndexStatus, error) {
+	key := cache.Key(imagePath)
+
+	if !force {
+		if _, fresh := cache.lookup(key, imagePath); fresh {
+			return StatusUpToDate, nil
+		}
+	}
+
+	desc, err := cache.analyze(imagePath)
+	if err != nil {
+		return StatusFailed, err
+	}
+	if err := cache.Store.Put(key, desc); err != nil {
+		return StatusFailed, fmt.Errorf("sauvegarde : %w", err)
+	}
+	return StatusGenerated, nil
+}
+
+/*
+===== LISTE DES IMAGES D'UN DOSSIER =====
+
+Retourne les fichiers du dossier ET de ses sous-dossiers dont l'extension
+correspond à un format décodable (JPEG, PNG, GIF), dans l'ordre lexical
+des chemins pour un ordre stable.
+*/
+func ListImages(dir string) ([]string, error) {
+	var images []string
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".jpg", ".jpeg", ".png", ".gif":
+			images = append(images, path)
+		}
+		return nil
+	})
+	return images, err
+}
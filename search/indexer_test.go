@@ -0,0 +1,14 @@
+package search
+
+import (
+	"errors"
+	"testing"
+)
+
+// Sans stockage, l'indexation échoue explicitement au lieu de paniquer dans un worker
+func TestIndexDirectoryNilStore(t *testing.T) {
+	report, err := IndexDirectory(t.TempDir(), nil, IndexOptions{})
+	if !errors.Is(err, errNoStore) || report != nil {
+		t.Errorf("bilan %v, erreur %v : errNoStore attendue", report, err)
+	}
+}
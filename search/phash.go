@@ -0,0 +1,168 @@
+package search
+
+import (
+	"fmt"
+
+	"github.com/MrIsmail1/Golang_images_matcher/index"
+	"github.com/MrIsmail1/Golang_images_matcher/model"
+)
+
+/*
+===== INDEX pHash DE LA BANQUE =====
+
+À QUOI ÇA SERT :
+Pré-filtre des requêtes : un BK-tree sur le pHash global de chaque image
+de la banque donne en temps sous-linéaire les images proches de la
+requête, et seules celles-ci passent par compare.CompareDescriptors.
+
+CONSTRUCTION :
+Au premier usage, chaque entrée est lue une fois pour son pHash global :
+résumé seul pour un index binaire (model.BinaryIndex.Summary), descripteur
+complet pour un stockage. Un arbre par jeu de paramètres d'analyse : une
+requête ne reçoit que des candidats comparables, aucun candidat n'est
+perdu au profit d'une image qui serait ensuite écartée. Les descripteurs
+illisibles, d'un autre schéma ou d'autres paramètres que la requête sont
+signalés dans Result.Skipped de chaque requête filtrée, comme lors d'un
+parcours complet.
+*/
+type phashIndex struct {
+	params     []model.AnalysisParams // Paramètres de chaque arbre
+	trees      []*index.BKTree
+	group      []int     // Arbre de chaque entrée (-1 : entrée ignorée)
+	identities []string  // Identité de chaque entrée (auto-exclusion sans recharger)
+	skipped    []Skipped // Entrées illisibles ou incompatibles lors de la construction
+}
+
+// tree retourne le numéro de l'arbre des descripteurs de paramètres params, -1 si aucun
+func (px *phashIndex) tree(params model.AnalysisParams) int {
+	for g, p := range px.params {
+		if p == params {
+			return g
+		}
+	}
+	return -1
+}
+
+// phashIndex retourne l'index pHash du moteur, construit au premier appel
+func (e *Engine) phashIndex() *phashIndex {
+	e.phashOnce.Do(func() {
+		px := &phashIndex{
+			group:      make([]int, len(e.entries)),
+			identities: make([]string, len(e.entries)),
+		}
+		for i, en := range e.entries {
+			px.group[i] = -1
+			desc, err := en.summary()
+			if err != nil {
+				px.skipped = append(px.skipped, Skipped{Path: en.path, Err: err})
+				continue
+			}
+			if desc.SchemaVersion != model.SchemaVersion {
+				px.skipped = append(px.skipped, Skipped{Path: en.name(), Err: &model.DescriptorError{
+					Path: en.name(), Kind: model.ErrIncompatible, Err: &model.IncompatibleError{
+						Reason: fmt.Sprintf("schéma v%d, v%d attendu", desc.SchemaVersion, model.SchemaVersion),
+					},
+				}})
+				continue
+			}
+			g := px.tree(desc.Params)
+			if g < 0 {
+				g = len(px.trees)
+				px.params = append(px.params, desc.Params)
+				px.trees = append(px.trees, index.NewBKTree())
+			}
+			px.group[i] = g
+			px.identities[i] = identity(desc)
+			px.trees[g].Add(uint64(desc.GlobalPHash), i)
+		}
+		e.phash = px
+	})
+	return e.phash
+}
+
+/*
+===== ENTRÉES À COMPARER =====
+
+Sans pré-filtre : toutes les entrées de la banque.
+Avec pré-filtre : les candidats de l'index pHash ou de l'index vectoriel.
+*/
+func (e *Engine) candidates(desc *model.FullImageDescriptor, opts Options) ([]int, []Skipped, error) {
+	usePHash := opts.PHashCandidates > 0 || opts.PHashRadius > 0
+	switch {
+	case usePHash && opts.VectorCandidates > 0:
+		return nil, nil, fmt.Errorf("search: pré-filtres pHash et vectoriel exclusifs")
+	case usePHash:
+		targets, skipped := e.phashCandidates(desc, opts)
+		return targets, skipped, nil
+	case opts.VectorCandidates > 0:
+		return e.vectorCandidates(desc, opts)
+	}
+
+	all := make([]int, len(e.entries))
+	for i := range all {
+		all[i] = i
+	}
+	return all, nil, nil
+}
+
+/*
+===== CANDIDATS DE L'INDEX pHash =====
+
+Du plus proche au plus lointain, l'image de requête elle-même n'occupant
+pas de place parmi les PHashCandidates (sauf IncludeSelf).
+*/
+func (e *Engine) phashCandidates(desc *model.FullImageDescriptor, opts Options) ([]int, []Skipped) {
+	px := e.phashIndex()
+	h := uint64(desc.GlobalPHash)
+
+	// Arbre des images de mêmes paramètres que la requête (aucune : liste vide)
+	tree := index.NewBKTree()
+	g := px.tree(desc.Params)
+	if g >= 0 {
+		tree = px.trees[g]
+	}
+
+	var neighbors []index.Neighbor
+	switch {
+	case opts.PHashCandidates <= 0:
+		neighbors = tree.Within(h, opts.PHashRadius)
+	default:
+		maxDistance := -1
+		if opts.PHashRadius > 0 {
+			maxDistance = opts.PHashRadius
+		}
+		k := opts.PHashCandidates
+		if !opts.IncludeSelf {
+			k++ // Place de l'image de requête, retirée ci-dessous
+		}
+		neighbors = tree.Nearest(h, k, maxDistance)
+	}
+
+	self := identity(desc)
+	targets := make([]int, 0, len(neighbors))
+	for _, nb := range neighbors {
+		if !opts.IncludeSelf && px.identities[nb.ID] == self {
+			continue
+		}
+		targets = append(targets, nb.ID)
+	}
+	if opts.PHashCandidates > 0 && len(targets) > opts.PHashCandidates {
+		targets = targets[:opts.PHashCandidates]
+	}
+
+	skipped := make([]Skipped, len(px.skipped))
+	copy(skipped, px.skipped)
+
+	// Images d'autres paramètres : jamais candidates, signalées comme au parcours complet
+	if len(px.trees) > 1 || g < 0 {
+		for i, other := range px.group {
+			if other >= 0 && other != g {
+				en := e.entries[i]
+				skipped = append(skipped, Skipped{Path: en.name(), Err: &model.DescriptorError{
+					Path: en.name(), Kind: model.ErrIncompatible, Err: errIncompatibleQuery,
+				}})
+			}
+		}
+	}
+	return targets, skipped
+}
@@ -0,0 +1,37 @@
+package search
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/MrIsmail1/Golang_images_matcher/analyzer"
+	"github.com/MrIsmail1/Golang_images_matcher/model"
+)
+
+// Un descripteur d'autres paramètres, même de pHash identique, n'occupe pas de place de candidat
+func TestPHashSkipsIncompatibleParams(t *testing.T) {
+	images, descs := bankDescriptors(t, 3)
+
+	// Jumeau de la requête analysé avec une pyramide : pHash identique, paramètres différents
+	params := model.CurrentParams()
+	params.Pyramid = "2,4"
+	twin, err := analyzer.AnalyzeImageWith(images[0], params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	twin.ImageName = "jumeau-" + filepath.Base(images[0])
+	twin.Source = nil
+
+	engine := NewEngine(append(descs[1:], twin))
+	res, err := engine.Query(descs[0], Options{PHashCandidates: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Matches) != 1 || res.Matches[0].ImageName == twin.ImageName {
+		t.Errorf("candidats %v, une image de la banque comparable attendue", res.Matches)
+	}
+	if len(res.Skipped) != 1 || res.Skipped[0].Path != twin.ImageName || !errors.Is(res.Skipped[0].Err, model.ErrIncompatible) {
+		t.Errorf("ignorés %v, le jumeau seul attendu (incompatible)", res.Skipped)
+	}
+}
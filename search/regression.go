@@ -0,0 +1,78 @@
+package search
+
+import (
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"math"
+	"os"
+
+	"github.com/MrIsmail1/Golang_images_matcher/model"
+	drawx "golang.org/x/image/draw"
+)
+
+/*
+===== OUTILS DE NON-RÉGRESSION DU SCORE =====
+
+À QUOI ÇA SERT :
+Partagés par la sous-commande check et les tests du moteur : une copie
+dégradée d'une image de la banque doit la retrouver en tête du classement.
+*/
+
+/*
+===== RANG DE L'IMAGE D'ORIGINE =====
+
+1 + nombre d'images au score strictement meilleur : un doublon exact de
+l'original dans la banque (même score) ne le fait pas reculer.
+L'original est reconnu par son identité (chemin source, sinon nom).
+Retourne 0 s'il est absent du classement.
+*/
+func OriginalRank(matches []Match, original *model.FullImageDescriptor) int {
+	want := identity(original)
+	for _, m := range matches {
+		name := m.SourcePath
+		if name == "" {
+			name = m.ImageName
+		}
+		if name != want {
+			continue
+		}
+		rank := 1
+		for _, other := range matches {
+			if other.Score > m.Score {
+				rank++
+			}
+		}
+		return rank
+	}
+	return 0
+}
+
+// WriteJPEGCopy écrit une copie JPEG de l'image, redimensionnée d'un facteur scale
+func WriteJPEGCopy(src, dst string, scale float64, quality int) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	img, _, err := image.Decode(f)
+	f.Close()
+	if err != nil {
+		return err
+	}
+
+	b := img.Bounds()
+	w := max(1, int(math.Round(float64(b.Dx())*scale)))
+	h := max(1, int(math.Round(float64(b.Dy())*scale)))
+	resized := image.NewRGBA(image.Rect(0, 0, w, h))
+	drawx.ApproxBiLinear.Scale(resized, resized.Bounds(), img, b, draw.Over, nil)
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if err := jpeg.Encode(out, resized, &jpeg.Options{Quality: quality}); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,74 @@
+package search
+
+import "container/heap"
+
+/*
+===== TAS BORNÉ DES K MEILLEURS RÉSULTATS =====
+
+À QUOI ÇA SERT :
+Garde uniquement les K meilleures correspondances pendant le parcours de la
+banque, sans stocker ni trier toutes les comparaisons (mémoire O(K)).
+
+PRINCIPE :
+Tas "min" : la racine est la PIRE des K correspondances gardées.
+Un nouveau candidat n'entre que s'il bat cette racine, qu'il remplace.
+*/
+type topK struct {
+	k     int // ≤ 0 = aucune limite
+	items matchHeap
+}
+
+func newTopK(k int) *topK {
+	return &topK{k: k}
+}
+
+// push propose une correspondance au tas
+func (t *topK) push(m Match) {
+	if t.k <= 0 || t.items.Len() < t.k {
+		heap.Push(&t.items, m)
+		return
+	}
+	if better(m, t.items[0]) {
+		t.items[0] = m
+		heap.Fix(&t.items, 0)
+	}
+}
+
+// sorted retourne les correspondances gardées, de la meilleure à la pire
+func (t *topK) sorted() []Match {
+	out := make([]Match, len(t.items))
+	copy(out, t.items)
+	sortMatches(out)
+	return out
+}
+
+/*
+===== ORDRE DES CORRESPONDANCES =====
+
+Score décroissant, puis nom d'image et chemin du descripteur :
+deux exécutions identiques donnent toujours le même classement.
+*/
+func better(a, b Match) bool {
+	if a.Score != b.Score {
+		return a.Score > b.Score
+	}
+	if a.ImageName != b.ImageName {
+		return a.ImageName < b.ImageName
+	}
+	return a.DescriptorPath < b.DescriptorPath
+}
+
+// matchHeap implémente heap.Interface avec la pire correspondance en racine
+type matchHeap []Match
+
+func (h matchHeap) Len() int           { return len(h) }
+func (h matchHeap) Less(i, j int) bool { return better(h[j], h[i]) }
+func (h matchHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *matchHeap) Push(x any)        { *h = append(*h, x.(Match)) }
+func (h *matchHeap) Pop() any {
+	old := *h
+	n := len(old)
+	m := old[n-1]
+	*h = old[:n-1]
+	return m
+}
@@ -0,0 +1,409 @@
+package search
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/MrIsmail1/Golang_images_matcher/index"
+	"github.com/MrIsmail1/Golang_images_matcher/model"
+)
+
+/*
+===== INDEX VECTORIEL DE LA BANQUE =====
+
+À QUOI ÇA SERT :
+Génération de candidats pour les grosses banques : chaque descripteur est
+aplati en vecteur (index.FlattenDescriptor) et rangé dans un index HNSW.
+Une requête ne compare ensuite avec compare.CompareDescriptors que les
+Options.VectorCandidates images les plus proches dans cet espace.
+
+IDENTIFIANTS :
+Les identifiants HNSW sont propres à l'index et associés au nom de
+l'entrée (clé du stockage, sinon chemin du descripteur). Un index
+sauvegardé reste ainsi utilisable après l'ajout ou la suppression
+d'images (voir LoadVectorIndex).
+*/
+type vectorIndex struct {
+	hnsw     *index.HNSW
+	opts     index.VectorOptions
+	params   model.AnalysisParams
+	labels   map[int]vectorLabel // Identifiant HNSW → entrée
+	entryOf  map[int]int         // Identifiant HNSW → position dans e.entries
+	nextID   int
+	skipped  []Skipped // Entrées illisibles ou incompatibles lors de la construction
+	modified bool      // Modifié depuis la construction ou le chargement
+}
+
+// vectorLabel : ce qu'un index sauvegardé retient de chaque entrée
+type vectorLabel struct {
+	name     string // Nom de l'entrée (entry.label)
+	identity string // Identité de l'image (auto-exclusion sans recharger)
+}
+
+/*
+===== RÉGLAGES DE L'INDEX VECTORIEL =====
+
+La valeur zéro est utilisable : vecteurs globaux seuls, réglages HNSW par défaut.
+*/
+type VectorIndexOptions struct {
+	Vector index.VectorOptions
+	HNSW   index.HNSWConfig
+}
+
+// errNoVectorIndex : sauvegarde demandée sans index construit ni chargé
+var errNoVectorIndex = errors.New("search: aucun index vectoriel")
+
+/*
+===== CONSTRUCTION DE L'INDEX VECTORIEL =====
+
+Charge chaque descripteur de la banque une fois et l'ajoute à un nouvel
+index, qui remplace le précédent. Les descripteurs illisibles ou produits
+avec d'autres paramètres d'analyse sont signalés dans Result.Skipped des
+requêtes qui l'utilisent.
+*/
+func (e *Engine) BuildVectorIndex(opts VectorIndexOptions) {
+	vi := e.newVectorIndex(opts)
+	e.vectorMu.Lock()
+	e.vector = vi
+	e.vectorMu.Unlock()
+}
+
+// newVectorIndex construit un index vectoriel sur toutes les entrées du moteur
+func (e *Engine) newVectorIndex(opts VectorIndexOptions) *vectorIndex {
+	params := e.cache.params()
+	vi := &vectorIndex{
+		hnsw:     index.NewHNSW(index.VectorLen(params, opts.Vector), opts.HNSW),
+		opts:     opts.Vector,
+		params:   params,
+		labels:   make(map[int]vectorLabel),
+		entryOf:  make(map[int]int),
+		modified: true,
+	}
+	for i := range e.entries {
+		vi.add(e.entries[i], i)
+	}
+	return vi
+}
+
+// add aplatit le descripteur de l'entrée et l'ajoute à l'index
+func (vi *vectorIndex) add(en entry, pos int) {
+	desc, err := en.descriptor()
+	if err != nil {
+		vi.skipped = append(vi.skipped, Skipped{Path: en.path, Err: err})
+		return
+	}
+	var vec []float32
+	err = model.CheckCompatibilityWith(desc, vi.params)
+	if err == nil {
+		vec, err = index.FlattenDescriptor(desc, vi.opts)
+	}
+	if err == nil {
+		err = vi.hnsw.Add(vi.nextID, vec)
+	}
+	if err != nil {
+		vi.skipped = append(vi.skipped, Skipped{Path: en.name(), Err: &model.DescriptorError{
+			Path: en.name(), Kind: model.ErrIncompatible, Err: err,
+		}})
+		return
+	}
+
+	vi.labels[vi.nextID] = vectorLabel{name: en.label(), identity: identity(desc)}
+	vi.entryOf[vi.nextID] = pos
+	vi.nextID++
+	vi.modified = true
+}
+
+// label identifie l'entrée dans un index sauvegardé : clé du stockage, indépendante de son emplacement
+func (en entry) label() string {
+	if en.key != "" {
+		return en.key
+	}
+	return en.name()
+}
+
+// vectorIndex retourne l'index vectoriel du moteur, construit avec les réglages par défaut si besoin
+func (e *Engine) vectorIndex() *vectorIndex {
+	e.vectorMu.Lock()
+	defer e.vectorMu.Unlock()
+	if e.vector == nil {
+		e.vector = e.newVectorIndex(VectorIndexOptions{})
+	}
+	return e.vector
+}
+
+// VectorIndexModified indique si l'index vectoriel a changé depuis sa construction ou son chargement
+func (e *Engine) VectorIndexModified() bool {
+	e.vectorMu.Lock()
+	defer e.vectorMu.Unlock()
+	return e.vector != nil && e.vector.modified
+}
+
+/*
+===== CANDIDATS DE L'INDEX VECTORIEL =====
+
+Les k entrées les plus proches de la requête dans l'espace vectoriel,
+l'image de requête elle-même n'occupant pas de place (sauf IncludeSelf).
+*/
+func (e *Engine) vectorCandidates(desc *model.FullImageDescriptor, opts Options) ([]int, []Skipped, error) {
+	vi := e.vectorIndex()
+	if desc.Params != vi.params {
+		return nil, nil, fmt.Errorf("search: index vectoriel construit pour les paramètres %+v, requête en %+v", vi.params, desc.Params)
+	}
+	vec, err := index.FlattenDescriptor(desc, vi.opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	k := opts.VectorCandidates
+	if !opts.IncludeSelf {
+		k++ // Place de l'image de requête, retirée ci-dessous
+	}
+	neighbors, err := vi.hnsw.Search(vec, k)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	self := identity(desc)
+	targets := make([]int, 0, len(neighbors))
+	for _, nb := range neighbors {
+		if !opts.IncludeSelf && vi.labels[nb.ID].identity == self {
+			continue
+		}
+		targets = append(targets, vi.entryOf[nb.ID])
+	}
+	if len(targets) > opts.VectorCandidates {
+		targets = targets[:opts.VectorCandidates]
+	}
+
+	skipped := make([]Skipped, len(vi.skipped))
+	copy(skipped, vi.skipped)
+	return targets, skipped, nil
+}
+
+/*
+===== FORMAT DE SAUVEGARDE =====
+
+Tout en little-endian :
+
+	magic "GISVIDX2"
+	réglages : tuiles (uint8), paramètres d'analyse (longueur uint32 + JSON)
+	entrées  : nombre (uint32), puis pour chacune : identifiant (int64),
+	           nom et identité (longueur uint32 + octets)
+	index HNSW (voir index.HNSW.Save)
+
+Les fichiers "GISVIDX1" (paramètres en 3 × uint32 : taille, bins, grille)
+restent lisibles, comme les paramètres JSON écrits par une version
+antérieure (voir model.UpgradeParams).
+*/
+const (
+	vectorMagic   = "GISVIDX2"
+	vectorMagicV1 = "GISVIDX1"
+)
+
+// Bornes des longueurs lues avant toute allocation (fichier corrompu)
+const (
+	maxVectorParamsSize = 1 << 16 // Paramètres d'analyse en JSON
+	maxVectorLabelSize  = 1 << 16 // Nom ou identité d'une entrée
+	maxVectorEntries    = 1 << 24
+)
+
+// SaveVectorIndex écrit l'index vectoriel du moteur dans w
+func (e *Engine) SaveVectorIndex(w io.Writer) error {
+	e.vectorMu.Lock()
+	vi := e.vector
+	e.vectorMu.Unlock()
+	if vi == nil {
+		return errNoVectorIndex
+	}
+
+	params, err := json.Marshal(vi.params)
+	if err != nil {
+		return err
+	}
+
+	le := binary.LittleEndian
+	b := []byte(vectorMagic)
+	tiles := byte(0)
+	if vi.opts.Tiles {
+		tiles = 1
+	}
+	b = append(b, tiles)
+	b = le.AppendUint32(b, uint32(len(params)))
+	b = append(b, params...)
+
+	ids := make([]int, 0, len(vi.labels))
+	for id := range vi.labels {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	b = le.AppendUint32(b, uint32(len(ids)))
+	for _, id := range ids {
+		b = le.AppendUint64(b, uint64(id))
+		for _, s := range []string{vi.labels[id].name, vi.labels[id].identity} {
+			b = le.AppendUint32(b, uint32(len(s)))
+			b = append(b, s...)
+		}
+	}
+	if _, err := w.Write(b); err != nil {
+		return err
+	}
+	if err := vi.hnsw.Save(w); err != nil {
+		return err
+	}
+
+	e.vectorMu.Lock()
+	vi.modified = false
+	e.vectorMu.Unlock()
+	return nil
+}
+
+/*
+===== CHARGEMENT DE L'INDEX VECTORIEL =====
+
+Relit un index écrit par SaveVectorIndex et le met à jour avec la banque
+actuelle du moteur : les entrées disparues sont supprimées de l'index,
+les nouvelles y sont ajoutées. Un index construit avec d'autres paramètres
+d'analyse que ceux du moteur est reconstruit entièrement.
+VectorIndexModified indique ensuite s'il faut le sauvegarder à nouveau.
+
+LIMITE :
+Une image modifiée sans changer de nom garde son ancien vecteur : les
+candidats peuvent en souffrir, pas les scores (recalculés à partir des
+descripteurs). Reconstruire l'index après une réindexation massive.
+*/
+func (e *Engine) LoadVectorIndex(r io.Reader) error {
+	br := bufio.NewReader(r)
+	le := binary.LittleEndian
+
+	read := func(n int) ([]byte, error) {
+		buf := make([]byte, n)
+		if _, err := io.ReadFull(br, buf); err != nil {
+			return nil, fmt.Errorf("%w : fichier tronqué : %v", index.ErrHNSW, err)
+		}
+		return buf, nil
+	}
+	// readBytes lit une longueur (uint32) bornée par limit, puis autant d'octets
+	readBytes := func(limit int, what string) ([]byte, error) {
+		b, err := read(4)
+		if err != nil {
+			return nil, err
+		}
+		n := int(le.Uint32(b))
+		if n > limit {
+			return nil, fmt.Errorf("%w : %s de %d octets (max %d)", index.ErrHNSW, what, n, limit)
+		}
+		return read(n)
+	}
+
+	head, err := read(len(vectorMagic) + 1)
+	if err != nil {
+		return err
+	}
+	vi := &vectorIndex{
+		opts:    index.VectorOptions{Tiles: head[len(vectorMagic)] == 1},
+		labels:  make(map[int]vectorLabel),
+		entryOf: make(map[int]int),
+	}
+
+	switch string(head[:len(vectorMagic)]) {
+	case vectorMagic:
+		b, err := readBytes(maxVectorParamsSize, "paramètres")
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(b, &vi.params); err != nil {
+			return fmt.Errorf("%w : paramètres : %v", index.ErrHNSW, err)
+		}
+	case vectorMagicV1:
+		b, err := read(3 * 4)
+		if err != nil {
+			return err
+		}
+		vi.params = model.AnalysisParams{
+			StandardSize: int(le.Uint32(b[0:])),
+			Bins:         int(le.Uint32(b[4:])),
+			TilesPerRow:  int(le.Uint32(b[8:])),
+		}
+	default:
+		return fmt.Errorf("%w : signature inconnue", index.ErrHNSW)
+	}
+	vi.params = model.UpgradeParams(vi.params) // Champs absents des anciens fichiers
+	if err := vi.params.Validate(); err != nil {
+		return fmt.Errorf("%w : %v", index.ErrHNSW, err)
+	}
+
+	// Autres paramètres d'analyse que ceux du moteur : vecteurs inutilisables
+	if vi.params != e.cache.params() {
+		e.BuildVectorIndex(VectorIndexOptions{Vector: vi.opts})
+		return nil
+	}
+
+	b, err := read(4)
+	if err != nil {
+		return err
+	}
+	count := int(le.Uint32(b))
+	if count > maxVectorEntries {
+		return fmt.Errorf("%w : %d entrées (max %d)", index.ErrHNSW, count, maxVectorEntries)
+	}
+	for i := 0; i < count; i++ {
+		b, err := read(8)
+		if err != nil {
+			return err
+		}
+		id := int64(le.Uint64(b))
+		if id < 0 {
+			return fmt.Errorf("%w : identifiant %d hors limites", index.ErrHNSW, id)
+		}
+		var strs [2]string
+		for j := range strs {
+			b, err := readBytes(maxVectorLabelSize, "nom d'entrée")
+			if err != nil {
+				return err
+			}
+			strs[j] = string(b)
+		}
+		vi.labels[int(id)] = vectorLabel{name: strs[0], identity: strs[1]}
+		vi.nextID = max(vi.nextID, int(id)+1)
+	}
+
+	if vi.hnsw, err = index.LoadHNSW(br, 0); err != nil {
+		return err
+	}
+	if vi.hnsw.Dim() != index.VectorLen(vi.params, vi.opts) {
+		return fmt.Errorf("%w : dimension %d incohérente avec les paramètres", index.ErrHNSW, vi.hnsw.Dim())
+	}
+
+	// Rapprochement avec la banque actuelle
+	positions := make(map[string]int, len(e.entries))
+	for i, en := range e.entries {
+		positions[en.label()] = i
+	}
+	known := make(map[string]bool, len(vi.labels))
+	for id, label := range vi.labels {
+		pos, ok := positions[label.name]
+		if !ok || !vi.hnsw.Contains(id) {
+			vi.hnsw.Delete(id)
+			delete(vi.labels, id)
+			vi.modified = true
+			continue
+		}
+		vi.entryOf[id] = pos
+		known[label.name] = true
+	}
+	for i, en := range e.entries {
+		if !known[en.label()] {
+			vi.add(en, i)
+		}
+	}
+
+	e.vectorMu.Lock()
+	e.vector = vi
+	e.vectorMu.Unlock()
+	return nil
+}
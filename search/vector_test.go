@@ -0,0 +1,82 @@
+package search
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/MrIsmail1/Golang_images_matcher/index"
+)
+
+// vectorEngine : moteur sur les premières images de la banque, index vectoriel construit
+func vectorEngine(t *testing.T, n int) *Engine {
+	t.Helper()
+	_, descs := bankDescriptors(t, n)
+	engine := NewEngine(descs)
+	engine.BuildVectorIndex(VectorIndexOptions{})
+	return engine
+}
+
+// Un index relu par un moteur sur la même banque est utilisable tel quel
+func TestVectorIndexSaveLoad(t *testing.T) {
+	engine := vectorEngine(t, 4)
+	var buf bytes.Buffer
+	if err := engine.SaveVectorIndex(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if engine.VectorIndexModified() {
+		t.Error("index modifié après sauvegarde")
+	}
+
+	if err := engine.LoadVectorIndex(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if engine.VectorIndexModified() {
+		t.Error("index relu sans changement de la banque marqué modifié")
+	}
+
+	desc, err := engine.entries[0].descriptor()
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := engine.Query(desc, Options{VectorCandidates: 2, IncludeSelf: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Matches) == 0 || res.Matches[0].ImageName != desc.ImageName {
+		t.Errorf("image de requête absente de la tête des candidats : %v", res.Matches)
+	}
+}
+
+// Les longueurs aberrantes d'un fichier corrompu sont refusées avant allocation
+func TestVectorIndexLoadCorrupt(t *testing.T) {
+	engine := vectorEngine(t, 2)
+	var buf bytes.Buffer
+	if err := engine.SaveVectorIndex(&buf); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	le := binary.LittleEndian
+	paramsAt := len(vectorMagic) + 1
+	countAt := paramsAt + 4 + int(le.Uint32(data[paramsAt:]))
+	labelAt := countAt + 4 + 8
+	corrupt := func(at int) []byte {
+		b := bytes.Clone(data)
+		le.PutUint32(b[at:], math.MaxUint32)
+		return b
+	}
+	cases := map[string][]byte{
+		"tronqué":             data[:len(data)-3],
+		"longueur paramètres": corrupt(paramsAt),
+		"nombre d'entrées":    corrupt(countAt),
+		"longueur nom":        corrupt(labelAt),
+	}
+	for name, b := range cases {
+		if err := engine.LoadVectorIndex(bytes.NewReader(b)); !errors.Is(err, index.ErrHNSW) {
+			t.Errorf("%s : erreur %v, index.ErrHNSW attendue", name, err)
+		}
+	}
+}